@@ -1,19 +1,33 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/Reinami/configprovider/pkg/cryptography"
 	"github.com/Reinami/configprovider/pkg/provider"
+	"github.com/Reinami/configprovider/pkg/sources"
 )
 
 const version = "v0.1.0"
 
-var supportedAlgorithms = map[string]string{
-	"aesgcm": "AES-GCM encryption with 256-bit key",
+type options struct {
+	algorithm  string
+	secret     string
+	value      string
+	keyID      string
+	keyName    string
+	passphrase string
+	iterations int
+	keyFile    string
+	keyEnv     string
+	publicKey  string
+	in         string
+	out        string
 }
 
 func main() {
@@ -24,64 +38,178 @@ func main() {
 
 	command := os.Args[1]
 
+	switch command {
+	case "--help", "-h", "help":
+		showHelp()
+		return
+	case "--l", "--list-algorithms":
+		showAlgorithms()
+		return
+	case "--version":
+		fmt.Println("lockbox version", version)
+		return
+	case "keygen":
+		generateKeyPair(os.Args[2:])
+		return
+	}
+
 	fs := flag.NewFlagSet(command, flag.ExitOnError)
 
-	var algorithm, secret, value string
-	fs.StringVar(&algorithm, "c", "", "Crypto Algorithm (run --l to see a list of supported Algorithms)")
-	fs.StringVar(&algorithm, "crypto-algorithm", "", "Crypto Algorithm (run --l to see a list of supported Algorithms)")
-	fs.StringVar(&secret, "s", "", "Your secret key for encryption/decryption")
-	fs.StringVar(&secret, "secret-key", "", "Your secret key for encryption/decryption")
-	fs.StringVar(&value, "v", "", "Value to encrypt/decrypt")
-	fs.StringVar(&value, "value", "", "Value to encrypt/decrypt")
+	var opts options
+	fs.StringVar(&opts.algorithm, "c", "", "Crypto Algorithm (run --l to see a list of supported Algorithms)")
+	fs.StringVar(&opts.algorithm, "crypto-algorithm", "", "Crypto Algorithm (run --l to see a list of supported Algorithms)")
+	fs.StringVar(&opts.secret, "s", "", "Your secret key for encryption/decryption")
+	fs.StringVar(&opts.secret, "secret-key", "", "Your secret key for encryption/decryption")
+	fs.StringVar(&opts.value, "v", "", "Value to encrypt/decrypt")
+	fs.StringVar(&opts.value, "value", "", "Value to encrypt/decrypt")
+	fs.StringVar(&opts.keyID, "k", "", "Key ID recorded in the encrypted value")
+	fs.StringVar(&opts.keyID, "key-id", "", "Key ID recorded in the encrypted value")
+	fs.StringVar(&opts.keyName, "n", "", "Config key the value is bound to")
+	fs.StringVar(&opts.keyName, "key-name", "", "Config key the value is bound to")
+	fs.StringVar(&opts.passphrase, "p", "", "Passphrase to derive the key from")
+	fs.StringVar(&opts.passphrase, "passphrase", "", "Passphrase to derive the key from")
+	fs.IntVar(&opts.iterations, "kdf-iterations", cryptography.DefaultPBKDF2Iterations, "PBKDF2 iterations used with --passphrase")
+	fs.StringVar(&opts.keyFile, "key-file", "", "Read the secret key from a file")
+	fs.StringVar(&opts.keyEnv, "key-env", "", "Read the secret key from an environment variable")
+	fs.StringVar(&opts.publicKey, "pubkey", "", "Public key(s) the encrypt command encrypts to, comma separated, implies --c=x25519 or --c=x25519-multi")
+	fs.StringVar(&opts.in, "in", "", "File to read")
+	fs.StringVar(&opts.out, "out", "", "File to write, defaults to stdout")
 
 	err := fs.Parse(os.Args[2:])
 	if err != nil {
 		panic(err)
 	}
 
+	if opts.keyFile != "" || opts.keyEnv != "" {
+		secret, err := loadSecret(opts)
+		if err != nil {
+			printErr(err)
+			return
+		}
+		opts.secret = secret
+	}
+
+	if opts.passphrase != "" {
+		if opts.algorithm == "" {
+			opts.algorithm = cryptography.PassphraseAlgorithm
+		}
+		if opts.secret == "" {
+			opts.secret = opts.passphrase
+		}
+	}
+
+	isRecipientCommand := command == "add-recipient" || command == "remove-recipient"
+	if isRecipientCommand && opts.algorithm == "" {
+		opts.algorithm = cryptography.MultiRecipientAlgorithm
+	}
+
+	isFileCommand := command == "encrypt-file" || command == "decrypt-file" || command == "sign"
+	needsAlgorithm := command != "sign"
+
+	// encrypt and decrypt stream --in instead of taking a value
+	isStreamCommand := (command == "encrypt" || command == "decrypt") && opts.in != ""
+
+	// encrypt-file needs a symmetric key for its MAC, so --pubkey only
+	// applies to encrypt
+	if opts.publicKey != "" && command == "encrypt" {
+		if opts.algorithm == "" {
+			opts.algorithm = cryptography.X25519Algorithm
+			if strings.Contains(opts.publicKey, ",") {
+				opts.algorithm = cryptography.MultiRecipientAlgorithm
+			}
+		}
+		if opts.secret == "" {
+			opts.secret = opts.publicKey
+		}
+	}
+
+	// remove-recipient only needs the value and the public key to remove
+	needsSecret := command != "remove-recipient"
+
 	args := fs.Args()
-	if len(args) >= 2 {
-		if secret == "" {
-			secret = strings.TrimSpace(args[0])
+	if isFileCommand || isStreamCommand {
+		if len(args) >= 1 && opts.secret == "" {
+			opts.secret = strings.TrimSpace(args[0])
+		}
+	} else if len(args) >= 2 {
+		if opts.secret == "" {
+			opts.secret = strings.TrimSpace(args[0])
 		}
 
-		if value == "" {
+		if opts.value == "" {
 
-			value = strings.TrimSpace(args[1])
+			opts.value = strings.TrimSpace(args[1])
 		}
+	} else if len(args) == 1 && opts.value == "" && (opts.secret != "" || !needsSecret) {
+		opts.value = strings.TrimSpace(args[0])
 	}
 
-	if algorithm == "" || secret == "" || value == "" {
+	if isFileCommand && opts.in == "" {
+		fmt.Println("Error: --in is required")
+		showHelp()
+		return
+	}
+
+	hasInput := opts.value != "" || isFileCommand || isStreamCommand
+	if (needsAlgorithm && opts.algorithm == "") || (needsSecret && opts.secret == "") || !hasInput {
 		fmt.Println("Error: --crypto-algorithm, secret, and value are required")
 		showHelp()
 		return
 	}
 
+	if isRecipientCommand && opts.publicKey == "" {
+		fmt.Println("Error: --pubkey is required")
+		showHelp()
+		return
+	}
+
 	switch command {
 	case "encrypt":
-		encryptSecret(algorithm, secret, value)
+		if isStreamCommand {
+			encryptStream(opts)
+		} else {
+			encryptSecret(opts)
+		}
 	case "decrypt":
-		decryptSecret(algorithm, secret, value)
-	case "--help", "-h", "help":
-		showHelp()
-	case "--l", "--list-algorithms":
-		showAlgorithms()
-	case "--version":
-		fmt.Println("lockbox version", version)
+		if isStreamCommand {
+			decryptStream(opts)
+		} else {
+			decryptSecret(opts)
+		}
+	case "add-recipient":
+		addRecipient(opts)
+	case "remove-recipient":
+		removeRecipient(opts)
+	case "encrypt-file":
+		encryptFile(opts)
+	case "decrypt-file":
+		decryptFile(opts)
+	case "sign":
+		signFile(opts)
 	default:
 		fmt.Println("unknown command ", command)
 		showHelp()
 	}
 }
 
-func encryptSecret(algorithm string, secret string, value string) {
-	algo, err := getAlgorithm(algorithm, secret)
+func encryptSecret(opts options) {
+	algo, err := getAlgorithm(opts)
 	if err != nil {
 		printErr(err)
 		return
 	}
 
-	encryptedValue, err := algo.Encrypt(value)
+	var encryptedValue string
+	if opts.keyName != "" {
+		keyAwareEncrypter, ok := algo.(provider.KeyAwareEncrypter)
+		if !ok {
+			printErr(fmt.Errorf("%s does not support --key-name", opts.algorithm))
+			return
+		}
+		encryptedValue, err = keyAwareEncrypter.EncryptFor(opts.keyName, opts.value)
+	} else {
+		encryptedValue, err = algo.Encrypt(opts.value)
+	}
 	if err != nil {
 		printErr(err)
 		return
@@ -90,14 +218,24 @@ func encryptSecret(algorithm string, secret string, value string) {
 	fmt.Println(encryptedValue)
 }
 
-func decryptSecret(algorithm string, secret string, value string) {
-	algo, err := getAlgorithm(algorithm, secret)
+func decryptSecret(opts options) {
+	algo, err := getAlgorithm(opts)
 	if err != nil {
 		printErr(err)
 		return
 	}
 
-	decryptedValue, err := algo.Decrypt(value)
+	var decryptedValue string
+	if opts.keyName != "" {
+		keyAwareDecrypter, ok := algo.(provider.KeyAwareDecrypter)
+		if !ok {
+			printErr(fmt.Errorf("%s does not support --key-name", opts.algorithm))
+			return
+		}
+		decryptedValue, err = keyAwareDecrypter.DecryptFor(opts.keyName, opts.value)
+	} else {
+		decryptedValue, err = algo.Decrypt(opts.value)
+	}
 	if err != nil {
 		printErr(err)
 		return
@@ -106,13 +244,287 @@ func decryptSecret(algorithm string, secret string, value string) {
 	fmt.Println(decryptedValue)
 }
 
-func getAlgorithm(algorithm string, secret string) (provider.CryptoAlgorithm, error) {
+func encryptFile(opts options) {
+	algo, err := getAlgorithm(opts)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	data, err := os.ReadFile(opts.in)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	encrypted, err := sources.EncryptProperties(data, algo)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	writeOutput(opts, encrypted, 0644)
+}
+
+func decryptFile(opts options) {
+	algo, err := getAlgorithm(opts)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	data, err := os.ReadFile(opts.in)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	decrypted, err := sources.DecryptProperties(data, algo)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	writeOutput(opts, decrypted, 0600)
+}
+
+// encryptStream encrypts --in in segments, so large files are never held in
+// memory
+func encryptStream(opts options) {
+	algo, err := getAlgorithm(opts)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	streamEncrypter, ok := algo.(cryptography.StreamEncrypter)
+	if !ok {
+		printErr(fmt.Errorf("%s does not support --in", opts.algorithm))
+		return
+	}
+
+	copyStream(opts, 0644, func(in io.Reader, out io.Writer) error {
+		writer, err := streamEncrypter.EncryptWriter(out)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(writer, in)
+		if err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+}
+
+func decryptStream(opts options) {
+	algo, err := getAlgorithm(opts)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	streamDecrypter, ok := algo.(cryptography.StreamDecrypter)
+	if !ok {
+		printErr(fmt.Errorf("%s does not support --in", opts.algorithm))
+		return
+	}
+
+	copyStream(opts, 0600, func(in io.Reader, out io.Writer) error {
+		reader, err := streamDecrypter.DecryptReader(in)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(out, reader)
+		return err
+	})
+}
+
+// copyStream runs transform from --in to --out, or stdout when it isn't set.
+// A partially written --out is removed if transform fails.
+func copyStream(opts options, perm os.FileMode, transform func(in io.Reader, out io.Writer) error) {
+	in, err := os.Open(opts.in)
+	if err != nil {
+		printErr(err)
+		return
+	}
+	defer in.Close()
+
+	if opts.out == "" {
+		err = transform(in, os.Stdout)
+		if err != nil {
+			printErr(err)
+		}
+		return
+	}
+
+	out, err := os.OpenFile(opts.out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	err = transform(in, out)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(opts.out)
+		printErr(err)
+	}
+}
+
+// signFile writes a detached signature of --in to --out, or <in>.sig
+func signFile(opts options) {
+	signer, err := cryptography.NewEd25519Signer(opts.secret)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	data, err := os.ReadFile(opts.in)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	if opts.out == "" {
+		opts.out = opts.in + sources.SignatureSuffix
+	}
+
+	writeOutput(opts, []byte(signer.Sign(sources.SignedMessage(opts.in, data))+"\n"), 0644)
+}
+
+// writeOutput writes data to --out, or stdout when it isn't set
+func writeOutput(opts options, data []byte, perm os.FileMode) {
+	if opts.out == "" {
+		fmt.Print(string(data))
+		return
+	}
+
+	err := os.WriteFile(opts.out, data, perm)
+	if err != nil {
+		printErr(err)
+	}
+}
+
+// loadSecret reads the key text from --key-file or --key-env, it is decoded by
+// the algorithm like a key passed with --secret-key
+func loadSecret(opts options) (string, error) {
+	if opts.keyFile != "" {
+		return cryptography.ReadKeyFile(opts.keyFile)
+	}
+
+	return cryptography.ReadKeyEnv(opts.keyEnv)
+}
+
+func getAlgorithm(opts options) (provider.CryptoAlgorithm, error) {
+	algorithmOptions := cryptography.AlgorithmOptions{
+		KeyID:      opts.keyID,
+		Iterations: opts.iterations,
+	}
+	if opts.publicKey != "" {
+		algorithmOptions.PublicKeys = strings.Split(opts.publicKey, ",")
+	}
+
+	return cryptography.NewAlgorithm(opts.algorithm, opts.secret, algorithmOptions)
+}
+
+func addRecipient(opts options) {
+	if opts.algorithm != cryptography.MultiRecipientAlgorithm {
+		printErr(fmt.Errorf("%s does not support recipients", opts.algorithm))
+		return
+	}
+
+	algo, err := cryptography.NewMultiRecipientDecrypter(opts.secret)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	rewrappedValue, err := algo.AddRecipient(opts.value, opts.publicKey)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	fmt.Println(rewrappedValue)
+}
+
+func removeRecipient(opts options) {
+	if opts.algorithm != cryptography.MultiRecipientAlgorithm {
+		printErr(fmt.Errorf("%s does not support recipients", opts.algorithm))
+		return
+	}
+
+	rewrappedValue, err := cryptography.RemoveRecipient(opts.value, opts.publicKey)
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	fmt.Println(rewrappedValue)
+}
+
+func generateKeyPair(args []string) {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+
+	var algorithm, out string
+	fs.StringVar(&algorithm, "c", cryptography.X25519Algorithm, "Key type, x25519 to encrypt, ed25519 to sign or kms for a local KMS key file")
+	fs.StringVar(&algorithm, "crypto-algorithm", cryptography.X25519Algorithm, "Key type, x25519 to encrypt, ed25519 to sign or kms for a local KMS key file")
+	fs.StringVar(&out, "out", "", "Local KMS key file to create with --c=kms")
+
+	err := fs.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+
+	// The local KMS master key is never printed, only written to its file
+	if algorithm == cryptography.KMSAlgorithm {
+		if out == "" {
+			printErr(errors.New("keygen --c=kms requires --out"))
+			return
+		}
+
+		err = cryptography.GenerateLocalKMSKey(out)
+		if err != nil {
+			printErr(err)
+			return
+		}
+
+		fmt.Println("Local KMS key written to " + out + ", use it with --c=kms " + out)
+		return
+	}
+
+	var publicKey, privateKey string
 	switch algorithm {
-	case "aesgcm":
-		return cryptography.NewAESGCMCrypto(secret)
+	case cryptography.X25519Algorithm:
+		publicKey, privateKey, err = cryptography.GenerateX25519KeyPair()
+	case "ed25519":
+		publicKey, privateKey, err = cryptography.GenerateEd25519KeyPair()
+	default:
+		err = fmt.Errorf("keygen supports x25519, ed25519 and kms, not %s", algorithm)
+	}
+	if err != nil {
+		printErr(err)
+		return
+	}
+
+	if algorithm == "ed25519" {
+		fmt.Println("Public key (share it, used to verify signatures):")
+		fmt.Println("  " + publicKey)
+		fmt.Println("Private key (keep it secret, used to sign):")
+		fmt.Println("  " + privateKey)
+		return
 	}
 
-	return nil, fmt.Errorf("unsupported algorithm, %v", algorithm)
+	fmt.Println("Public key (share it, used to encrypt):")
+	fmt.Println("  " + publicKey)
+	fmt.Println("Private key (keep it secret, used to decrypt):")
+	fmt.Println("  " + privateKey)
 }
 
 func showHelp() {
@@ -120,29 +532,64 @@ func showHelp() {
   lockbox <command> [options] <secret> <value>
 
 Commands:
-  encrypt     Encrypt a value
-  decrypt     Decrypt a value
+  encrypt           Encrypt a value, or stream the file --in with --c=aesgcm
+  decrypt           Decrypt a value, or a file written by encrypt --in
+  keygen            Generate an x25519 key pair, an ed25519 signing key pair with --c=ed25519, or a local KMS key file with --c=kms --out=<path>
+  add-recipient     Wrap an x25519-multi value for another --pubkey, using your private key
+  remove-recipient  Remove --pubkey from an x25519-multi value
+  encrypt-file      Encrypt every value of the properties file --in, keeping keys readable
+  decrypt-file      Decrypt a file written by encrypt-file, verifying its MAC
+  sign              Write a detached ed25519 signature of --in to <in>.sig, using your private key
 
 Options:
-  --c, --crypto-algorithm   Required. The crypto algorithm to use (e.g., aesgcm)
-  --s, --secret-key         Optional. Secret key (can be passed positionally)
+  --c, --crypto-algorithm   Required. The crypto algorithm to use (e.g., aesgcm, aessiv)
+  --s, --secret-key         Optional. Secret key, raw or prefixed with base64: or hex: (can be passed positionally)
   --v, --value              Optional. Value to encrypt/decrypt (can be passed positionally)
+  --k, --key-id             Optional. Key ID recorded in the encrypted value (e.g., prod-2026)
+  --n, --key-name           Optional. Binds the value to the config key it is stored under (e.g., DB_PASSWORD)
+  --p, --passphrase         Optional. Derive the key from a passphrase, implies --c=aesgcm-pbkdf2
+  --kdf-iterations          Optional. PBKDF2 iterations used with --passphrase (default 600000, max 1000000)
+  --key-file                Optional. Read the secret key from a file (must not be group/world readable)
+  --key-env                 Optional. Read the secret key from an environment variable
+  --pubkey                  Optional. Public key(s) the encrypt command encrypts to, comma separated, implies --c=x25519 or --c=x25519-multi (not for encrypt-file)
+  --in                      Optional. Input file for encrypt, decrypt, encrypt-file, decrypt-file and sign
+  --out                     Optional. Output file for commands using --in, defaults to stdout (<in>.sig for sign)
   --l, --list-algorithms    Optional. Will display a list of currently supported algorithms
   --version                 Optional. Displays current version of lockbox
 
 Examples:
   lockbox encrypt --c=aesgcm mysecret myvalue
-  lockbox decrypt --c=aesgcm mysecret myencryptedvalue`)
+  lockbox encrypt --c=aesgcm --key-id=prod-2026 mysecret myvalue
+  lockbox encrypt --c=aesgcm --key-name=DB_PASSWORD mysecret myvalue
+  lockbox encrypt --passphrase="correct horse battery staple" myvalue
+  lockbox encrypt --c=aesgcm --key-env=CONFIG_KEY myvalue
+  lockbox encrypt --c=aesgcm --key-file=/etc/app/config.key myvalue
+  lockbox encrypt --pubkey=mypublickey myvalue
+  lockbox encrypt --pubkey=teamakey,teambkey myvalue
+  lockbox add-recipient --pubkey=teamckey myprivatekey myencryptedvalue
+  lockbox remove-recipient --pubkey=teamakey myencryptedvalue
+  lockbox decrypt --c=aesgcm mysecret myencryptedvalue
+  lockbox encrypt-file --c=aesgcm --in=app.properties --out=app.enc.properties mysecret
+  lockbox decrypt-file --c=aesgcm --in=app.enc.properties mysecret
+  lockbox encrypt --c=aesgcm --in=bundle.pem --out=bundle.pem.enc mysecret
+  lockbox decrypt --c=aesgcm --in=bundle.pem.enc --out=bundle.pem mysecret
+  lockbox keygen --c=ed25519
+  lockbox keygen --c=kms --out=dev-kms.key
+  lockbox encrypt --c=kms dev-kms.key myvalue
+  lockbox sign --in=app.properties mysigningkey
+  lockbox decrypt --c=x25519 myprivatekey myencryptedvalue`)
 }
 
 func showAlgorithms() {
 	fmt.Println("Supported Algorithms:")
-	for name, desc := range supportedAlgorithms {
-		fmt.Printf("  %-10s - %s\n", name, desc)
+	for _, algorithm := range cryptography.List() {
+		fmt.Printf("  %-20s - %s\n", algorithm.Name, algorithm.Description)
 	}
 }
 
+// printErr exits with status 1, so scripts notice e.g. a failed MAC check
 func printErr(err error) {
 	fmt.Println(err)
 	showHelp()
+	os.Exit(1)
 }
@@ -4,4 +4,9 @@ go 1.24.1
 
 require github.com/Reinami/configprovider v0.0.0
 
+require (
+	golang.org/x/crypto v0.48.0 // indirect
+	golang.org/x/sys v0.41.0 // indirect
+)
+
 replace github.com/Reinami/configprovider => ../
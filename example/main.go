@@ -7,12 +7,12 @@ import (
 )
 
 type AppConfig struct {
-	Debug     bool            `config:"DEBUG"`
-	Port      int             `config:"PORT,default=8080"`
-	Name      string          `config:"NAME,default=defaultName"`
-	SecretKey string          `config:"SECRET_KEY,encrypted"`
-	Tags      []string        `config:"TAGS"`
-	Flags     map[string]bool `config:"FLAGS"`
+	Debug     bool                  `config:"DEBUG"`
+	Port      int                   `config:"PORT,default=8080"`
+	Name      string                `config:"NAME,default=defaultName"`
+	SecretKey provider.SecretString `config:"SECRET_KEY,encrypted"`
+	Tags      []string              `config:"TAGS"`
+	Flags     map[string]bool       `config:"FLAGS"`
 }
 
 func main() {
@@ -34,7 +34,7 @@ func main() {
 	fmt.Printf("  Debug:       %v\n", config.Debug)
 	fmt.Printf("  Port:        %v\n", config.Port)
 	fmt.Printf("  Name:        %v\n", config.Name)
-	fmt.Printf("  SecretKey:   %v\n", config.SecretKey)
+	fmt.Printf("  SecretKey:   %v (%d bytes)\n", config.SecretKey, len(config.SecretKey.Reveal()))
 	fmt.Printf("  Tags:        %v\n", config.Tags)
 	fmt.Printf("  Flags:       %v\n", config.Flags)
 }
@@ -10,8 +10,17 @@ import (
 	"io"
 )
 
+const (
+	AESGCMAlgorithm = "aesgcm"
+	aesGCMVersion   = "v1"
+)
+
+// AESGCMCrypto is safe for concurrent use once it is set up, the AEAD is
+// created once by NewAESGCMCrypto and shared by every call
 type AESGCMCrypto struct {
-	key []byte
+	aead  cipher.AEAD
+	key   []byte
+	keyID string
 }
 
 func NewAESGCMCrypto(key string) (*AESGCMCrypto, error) {
@@ -19,62 +28,179 @@ func NewAESGCMCrypto(key string) (*AESGCMCrypto, error) {
 		return &AESGCMCrypto{}, errors.New("AESGCMDecrypter: key must be exactly 32 bytes (AES-256)")
 	}
 
+	aead, err := newAESGCM([]byte(key))
+	if err != nil {
+		return &AESGCMCrypto{}, err
+	}
+
 	return &AESGCMCrypto{
-		key: []byte(key),
+		aead: aead,
+		key:  []byte(key),
 	}, nil
 }
 
+// WithKeyID records keyID in the envelope of every encrypted value, and
+// refuses to decrypt envelopes that name a different key. Call it before
+// sharing the crypto between goroutines. It panics when ValidateKeyID refuses
+// keyID, an empty keyID records none.
+func (c *AESGCMCrypto) WithKeyID(keyID string) *AESGCMCrypto {
+	if keyID != "" {
+		err := ValidateKeyID(keyID)
+		if err != nil {
+			panic("cryptography: " + err.Error())
+		}
+	}
+
+	c.keyID = keyID
+	return c
+}
+
 func (c *AESGCMCrypto) Encrypt(plainText string) (string, error) {
-	aesCipher, err := aes.NewCipher(c.key)
+	return c.encrypt(plainText, nil)
+}
+
+// EncryptFor authenticates key as additional data, the value can then only be
+// decrypted with DecryptFor and the same key
+func (c *AESGCMCrypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+// Decrypt accepts both ENC[aesgcm,...] envelopes and bare base64 values
+// produced before the envelope format existed
+func (c *AESGCMCrypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+// DecryptFor decrypts values bound to key by EncryptFor, unbound values are
+// decrypted as with Decrypt
+func (c *AESGCMCrypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *AESGCMCrypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	final, err := sealAEAD(c.aead, []byte(plainText), additionalData)
 	if err != nil {
-		return "", fmt.Errorf("failed to create aes cipher: %w", err)
+		return "", err
 	}
 
-	gcm, err := cipher.NewGCM(aesCipher)
+	envelope := Envelope{
+		Algorithm: AESGCMAlgorithm,
+		Version:   aesGCMVersion,
+		Params:    map[string]string{},
+		Payload:   base64.StdEncoding.EncodeToString(final),
+	}
+	if c.keyID != "" {
+		envelope.Params["kid"] = c.keyID
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *AESGCMCrypto) decrypt(cipherText string, key []byte) (string, error) {
+	base64CipherText := cipherText
+	var additionalData []byte
+
+	if IsEnvelope(cipherText) {
+		envelope, err := ParseEnvelope(cipherText)
+		if err != nil {
+			return "", err
+		}
+
+		if envelope.Algorithm != AESGCMAlgorithm {
+			return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, AESGCMAlgorithm)
+		}
+		if envelope.Version != aesGCMVersion {
+			return "", fmt.Errorf("unsupported %s version %s", AESGCMAlgorithm, envelope.Version)
+		}
+
+		keyID := envelope.Params["kid"]
+		if keyID != "" && c.keyID != "" && keyID != c.keyID {
+			return "", fmt.Errorf("value was encrypted with key %s, not %s", keyID, c.keyID)
+		}
+
+		additionalData, err = boundAdditionalData(envelope, key)
+		if err != nil {
+			return "", err
+		}
+
+		base64CipherText = envelope.Payload
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(base64CipherText)
 	if err != nil {
-		return "", fmt.Errorf("failed to created gcm: %w", err)
+		return "", fmt.Errorf("base64 decode failed: %w", err)
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	_, err = io.ReadFull(rand.Reader, nonce)
+	plainText, err := openAEAD(c.aead, cipherData, additionalData)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate nonce: %w", err)
+		return "", err
 	}
 
-	cipherText := gcm.Seal(nil, nonce, []byte(plainText), nil)
-	final := append(nonce, cipherText...)
+	return string(plainText), nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	aesCipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes cipher: %w", err)
+	}
 
-	return base64.StdEncoding.EncodeToString(final), nil
+	gcm, err := cipher.NewGCM(aesCipher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+
+	return gcm, nil
 }
 
-func (c *AESGCMCrypto) Decrypt(base64CipherText string) (string, error) {
-	cipherData, err := base64.StdEncoding.DecodeString(base64CipherText)
+// sealAESGCM encrypts plainText with a random nonce and returns nonce||ciphertext
+func sealAESGCM(key []byte, plainText []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newAESGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("base64 decode failed: %w", err)
+		return nil, err
 	}
 
-	aesCipher, err := aes.NewCipher(c.key)
+	return sealAEAD(gcm, plainText, additionalData)
+}
+
+// openAESGCM decrypts nonce||ciphertext produced by sealAESGCM
+func openAESGCM(key []byte, cipherData []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newAESGCM(key)
 	if err != nil {
-		return "", fmt.Errorf("failed to create aes cipher: %w", err)
+		return nil, err
 	}
 
-	gcm, err := cipher.NewGCM(aesCipher)
+	return openAEAD(gcm, cipherData, additionalData)
+}
+
+// sealAEAD encrypts plainText with a random nonce and returns nonce||ciphertext
+func sealAEAD(aead cipher.AEAD, plainText []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plainText)+aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
 	if err != nil {
-		return "", fmt.Errorf("failed to create gcm: %w", err)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	nonceSize := gcm.NonceSize()
+	return aead.Seal(nonce, nonce, plainText, additionalData), nil
+}
+
+// openAEAD decrypts nonce||ciphertext produced by sealAEAD
+func openAEAD(aead cipher.AEAD, cipherData []byte, additionalData []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
 	if len(cipherData) < nonceSize {
-		return "", errors.New("ciphertext too short")
+		return nil, errors.New("ciphertext too short")
 	}
 
 	nonce := cipherData[:nonceSize]
-	cipherText := cipherData[nonceSize:]
+	cipherBytes := cipherData[nonceSize:]
 
-	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	plainText, err := aead.Open(nil, nonce, cipherBytes, additionalData)
 	if err != nil {
-		return "", fmt.Errorf("decryption failed: %w", err)
+		return nil, fmt.Errorf("decryption failed: %w", err)
 	}
 
-	return string(plainText), nil
+	return plainText, nil
 }
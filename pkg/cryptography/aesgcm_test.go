@@ -1,7 +1,9 @@
 package cryptography
 
 import (
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -80,3 +82,200 @@ func TestAESGCM_InvalidKeyLength(t *testing.T) {
 		t.Errorf("expected error for invalid key length")
 	}
 }
+
+func TestAESGCM_EncryptEmitsEnvelope(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.WithKeyID("prod-2026").Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	envelope, err := ParseEnvelope(encrypted)
+	if err != nil {
+		t.Fatalf("expected an envelope, got %s: %v", encrypted, err)
+	}
+
+	if envelope.Algorithm != "aesgcm" || envelope.Version != "v1" || envelope.Params["kid"] != "prod-2026" {
+		t.Errorf("unexpected envelope: %+v", envelope)
+	}
+}
+
+func TestAESGCM_DecryptLegacyBareBase64(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	decrypted, err := crypto.Decrypt("u4f2tGtCrw4xFK5nzslqv8T31mhyi5EtJPAY91/hAjrCP/RwvlO26cqJUg8EQiWE")
+	if err != nil {
+		t.Fatalf("decryption failed: %v", err)
+	}
+
+	if decrypted != "THIS IS A SECRET KEY" {
+		t.Errorf("expected legacy value to decrypt, got %s", decrypted)
+	}
+}
+
+func TestAESGCM_DecryptEnvelopeMismatch(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.WithKeyID("old").Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	tests := map[string]string{
+		encrypted:                 "encrypted with key old, not new",
+		"ENC[other,v1,dGVzdA==]":  "unsupported algorithm other",
+		"ENC[aesgcm,v9,dGVzdA==]": "unsupported aesgcm version v9",
+	}
+
+	crypto.WithKeyID("new")
+	for input, expected := range tests {
+		_, err = crypto.Decrypt(input)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got: %v", input, expected, err)
+		}
+	}
+}
+
+func TestAESGCM_BoundToKey(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Fatalf("expected bound value to decrypt, got %q, %v", decrypted, err)
+	}
+
+	_, err = crypto.DecryptFor("ADMIN_TOKEN", encrypted)
+	if err == nil || !strings.Contains(err.Error(), "decryption failed") {
+		t.Errorf("expected value moved to another key to fail, got: %v", err)
+	}
+
+	_, err = crypto.Decrypt(encrypted)
+	if err == nil || !strings.Contains(err.Error(), "value is bound to a config key") {
+		t.Errorf("expected bound value to require DecryptFor, got: %v", err)
+	}
+
+	// Stripping the aad marker must not let the value decrypt
+	stripped := strings.Replace(encrypted, "aad=key,", "", 1)
+	_, err = crypto.DecryptFor("DB_PASSWORD", stripped)
+	if err == nil {
+		t.Errorf("expected stripped aad marker to fail decryption")
+	}
+}
+
+func TestAESGCM_DecryptForUnboundValue(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected unbound value to decrypt, got %q, %v", decrypted, err)
+	}
+}
+
+func TestAESGCM_ConcurrentUse(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			original := fmt.Sprintf("value-%d", i)
+			encrypted, err := crypto.EncryptFor("KEY", original)
+			if err != nil {
+				t.Errorf("encryption failed: %v", err)
+				return
+			}
+
+			decrypted, err := crypto.DecryptFor("KEY", encrypted)
+			if err != nil || decrypted != original {
+				t.Errorf("expected %s, got %s, %v", original, decrypted, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkAESGCM_Encrypt(b *testing.B) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		b.Fatalf("error: %v", err)
+	}
+
+	for b.Loop() {
+		_, err := crypto.Encrypt("super-secret-value")
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAESGCM_Decrypt(b *testing.B) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		b.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("super-secret-value")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		_, err := crypto.Decrypt(encrypted)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAESGCM_DecryptParallel(b *testing.B) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		b.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("super-secret-value")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := crypto.Decrypt(encrypted)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
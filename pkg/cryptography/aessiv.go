@@ -0,0 +1,235 @@
+package cryptography
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const (
+	AESSIVAlgorithm = "aessiv"
+	aesSIVVersion   = "v1"
+	sivSize         = aes.BlockSize
+)
+
+// AESSIVCrypto implements deterministic authenticated encryption with AES-SIV
+// (RFC 5297). The same plaintext under the same key always produces the same
+// ciphertext, so re-encrypting an unchanged properties file gives an empty
+// diff. Equal values are visible as equal ciphertexts, which is the price of
+// determinism.
+type AESSIVCrypto struct {
+	macBlock cipher.Block
+	ctrBlock cipher.Block
+}
+
+// NewAESSIVCrypto takes a 32, 48 or 64 byte key, half of it is used for S2V
+// and half for CTR mode
+func NewAESSIVCrypto(key string) (*AESSIVCrypto, error) {
+	if len(key) != 32 && len(key) != 48 && len(key) != 64 {
+		return nil, errors.New("AESSIVCrypto: key must be 32, 48 or 64 bytes")
+	}
+
+	half := len(key) / 2
+
+	macBlock, err := aes.NewCipher([]byte(key[:half]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes cipher: %w", err)
+	}
+
+	ctrBlock, err := aes.NewCipher([]byte(key[half:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aes cipher: %w", err)
+	}
+
+	return &AESSIVCrypto{macBlock: macBlock, ctrBlock: ctrBlock}, nil
+}
+
+func (c *AESSIVCrypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *AESSIVCrypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *AESSIVCrypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *AESSIVCrypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *AESSIVCrypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	envelope := Envelope{
+		Algorithm: AESSIVAlgorithm,
+		Version:   aesSIVVersion,
+		Params:    map[string]string{},
+		Payload:   base64.StdEncoding.EncodeToString(c.seal([]byte(plainText), sivAdditionalData(additionalData)...)),
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *AESSIVCrypto) decrypt(cipherText string, key []byte) (string, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if envelope.Algorithm != AESSIVAlgorithm {
+		return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, AESSIVAlgorithm)
+	}
+	if envelope.Version != aesSIVVersion {
+		return "", fmt.Errorf("unsupported %s version %s", AESSIVAlgorithm, envelope.Version)
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	plainText, err := c.open(cipherData, sivAdditionalData(additionalData)...)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// seal returns V||C as defined by RFC 5297 section 2.6
+func (c *AESSIVCrypto) seal(plainText []byte, additionalData ...[]byte) []byte {
+	v := c.s2v(append(additionalData, plainText)...)
+
+	output := make([]byte, sivSize+len(plainText))
+	copy(output, v[:])
+	c.ctr(v, output[sivSize:], plainText)
+
+	return output
+}
+
+// open decrypts V||C as defined by RFC 5297 section 2.7
+func (c *AESSIVCrypto) open(cipherData []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(cipherData) < sivSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	var v [sivSize]byte
+	copy(v[:], cipherData[:sivSize])
+
+	plainText := make([]byte, len(cipherData)-sivSize)
+	c.ctr(v, plainText, cipherData[sivSize:])
+
+	t := c.s2v(append(additionalData, plainText)...)
+	if subtle.ConstantTimeCompare(t[:], v[:]) != 1 {
+		return nil, errors.New("decryption failed: message authentication failed")
+	}
+
+	return plainText, nil
+}
+
+func (c *AESSIVCrypto) ctr(v [sivSize]byte, dst []byte, src []byte) {
+	// Clear the 31st and 63rd rightmost bits so implementations can use
+	// 32 or 64 bit counters
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+
+	cipher.NewCTR(c.ctrBlock, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v is the string-to-vector PRF from RFC 5297 section 2.4, the last component
+// is the plaintext
+func (c *AESSIVCrypto) s2v(components ...[]byte) [sivSize]byte {
+	var zero [sivSize]byte
+	d := cmac(c.macBlock, zero[:])
+
+	for _, s := range components[:len(components)-1] {
+		d = dbl(d)
+		mac := cmac(c.macBlock, s)
+		subtle.XORBytes(d[:], d[:], mac[:])
+	}
+
+	last := components[len(components)-1]
+	if len(last) >= sivSize {
+		t := append([]byte{}, last...)
+		subtle.XORBytes(t[len(t)-sivSize:], t[len(t)-sivSize:], d[:])
+		return cmac(c.macBlock, t)
+	}
+
+	d = dbl(d)
+	var padded [sivSize]byte
+	copy(padded[:], last)
+	padded[len(last)] = 0x80
+	subtle.XORBytes(d[:], d[:], padded[:])
+
+	return cmac(c.macBlock, d[:])
+}
+
+// cmac is AES-CMAC as defined by RFC 4493
+func cmac(block cipher.Block, message []byte) [sivSize]byte {
+	var l [sivSize]byte
+	block.Encrypt(l[:], l[:])
+
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	blocks := (len(message) + sivSize - 1) / sivSize
+	complete := blocks > 0 && len(message)%sivSize == 0
+	if blocks == 0 {
+		blocks = 1
+	}
+
+	var lastBlock [sivSize]byte
+	lastStart := (blocks - 1) * sivSize
+	if complete {
+		subtle.XORBytes(lastBlock[:], message[lastStart:], k1[:])
+	} else {
+		copy(lastBlock[:], message[lastStart:])
+		lastBlock[len(message)-lastStart] = 0x80
+		subtle.XORBytes(lastBlock[:], lastBlock[:], k2[:])
+	}
+
+	var x [sivSize]byte
+	for i := 0; i < blocks-1; i++ {
+		subtle.XORBytes(x[:], x[:], message[i*sivSize:(i+1)*sivSize])
+		block.Encrypt(x[:], x[:])
+	}
+
+	subtle.XORBytes(x[:], x[:], lastBlock[:])
+	block.Encrypt(x[:], x[:])
+
+	return x
+}
+
+// dbl multiplies by x in GF(2^128)
+func dbl(b [sivSize]byte) [sivSize]byte {
+	var doubled [sivSize]byte
+
+	carry := b[0] >> 7
+	for i := 0; i < sivSize-1; i++ {
+		doubled[i] = b[i]<<1 | b[i+1]>>7
+	}
+	doubled[sivSize-1] = b[sivSize-1] << 1
+	doubled[sivSize-1] ^= 0x87 * carry
+
+	return doubled
+}
+
+func sivAdditionalData(additionalData []byte) [][]byte {
+	if additionalData == nil {
+		return nil
+	}
+
+	return [][]byte{additionalData}
+}
@@ -0,0 +1,138 @@
+package cryptography
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const testSIVKey string = "1234567890123456789012345678901234567890123456789012345678901234"
+
+func decodeTestHex(t *testing.T, value string) []byte {
+	t.Helper()
+
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatalf("invalid hex %s: %v", value, err)
+	}
+
+	return decoded
+}
+
+// RFC 5297 appendix A
+func TestAESSIV_TestVectors(t *testing.T) {
+	tests := []struct {
+		key            string
+		additionalData []string
+		plainText      string
+		expected       string
+	}{
+		{
+			key:            "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			additionalData: []string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+			plainText:      "112233445566778899aabbccddee",
+			expected:       "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+		},
+		{
+			key: "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+			additionalData: []string{
+				"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+				"102030405060708090a0",
+				"09f911029d74e35bd84156c5635688c0",
+			},
+			plainText: "7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+			expected:  "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+		},
+	}
+
+	for _, test := range tests {
+		crypto, err := NewAESSIVCrypto(string(decodeTestHex(t, test.key)))
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+
+		var additionalData [][]byte
+		for _, data := range test.additionalData {
+			additionalData = append(additionalData, decodeTestHex(t, data))
+		}
+
+		sealed := crypto.seal(decodeTestHex(t, test.plainText), additionalData...)
+		if hex.EncodeToString(sealed) != test.expected {
+			t.Errorf("expected %s, got %x", test.expected, sealed)
+		}
+
+		opened, err := crypto.open(sealed, additionalData...)
+		if err != nil || hex.EncodeToString(opened) != test.plainText {
+			t.Errorf("expected %s, got %x, %v", test.plainText, opened, err)
+		}
+	}
+}
+
+func TestAESSIV_Deterministic(t *testing.T) {
+	crypto, err := NewAESSIVCrypto(testSIVKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encryption1, err1 := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	encryption2, err2 := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err1 != nil || err2 != nil {
+		t.Fatalf("encryption failed: %v, %v", err1, err2)
+	}
+
+	if encryption1 != encryption2 {
+		t.Errorf("expected identical outputs for the same input, got %s and %s", encryption1, encryption2)
+	}
+
+	otherKey, _ := crypto.EncryptFor("ADMIN_TOKEN", "super-secret-value")
+	if otherKey == encryption1 {
+		t.Errorf("expected different outputs for different config keys")
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encryption1)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+
+	_, err = crypto.DecryptFor("ADMIN_TOKEN", encryption1)
+	if err == nil || !strings.Contains(err.Error(), "message authentication failed") {
+		t.Errorf("expected value moved to another key to fail, got: %v", err)
+	}
+}
+
+func TestAESSIV_Tampered(t *testing.T) {
+	crypto, err := NewAESSIVCrypto(testSIVKey[:32])
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for _, plainText := range []string{"", "short", "a value longer than a single aes block"} {
+		encrypted, err := crypto.Encrypt(plainText)
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+
+		decrypted, err := crypto.Decrypt(encrypted)
+		if err != nil || decrypted != plainText {
+			t.Errorf("expected round trip of %q, got %q, %v", plainText, decrypted, err)
+		}
+
+		envelope, _ := ParseEnvelope(encrypted)
+		envelope.Payload = "A" + envelope.Payload[1:]
+		if envelope.String() == encrypted {
+			envelope.Payload = "B" + envelope.Payload[1:]
+		}
+
+		_, err = crypto.Decrypt(envelope.String())
+		if err == nil {
+			t.Errorf("expected tampered value of %q to fail", plainText)
+		}
+	}
+}
+
+func TestAESSIV_InvalidKeyLength(t *testing.T) {
+	_, err := NewAESSIVCrypto("invalid-key")
+	if err == nil {
+		t.Errorf("expected error for invalid key length")
+	}
+}
@@ -0,0 +1,38 @@
+package cryptography
+
+import "io"
+
+type Encrypter interface {
+	Encrypt(plainText string) (string, error)
+}
+
+type Decrypter interface {
+	Decrypt(cipherText string) (string, error)
+}
+
+type CryptoAlgorithm interface {
+	Decrypter
+	Encrypter
+}
+
+// KeyAwareEncrypter binds a value to the config key it is stored under, so
+// the ciphertext can't be moved to another key
+type KeyAwareEncrypter interface {
+	EncryptFor(key string, plainText string) (string, error)
+}
+
+// KeyAwareDecrypter decrypts values bound to a config key by KeyAwareEncrypter
+type KeyAwareDecrypter interface {
+	DecryptFor(key string, cipherText string) (string, error)
+}
+
+// StreamEncrypter encrypts large data, e.g. files, in segments without
+// holding it in memory
+type StreamEncrypter interface {
+	EncryptWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// StreamDecrypter decrypts streams written by StreamEncrypter
+type StreamDecrypter interface {
+	DecryptReader(r io.Reader) (io.Reader, error)
+}
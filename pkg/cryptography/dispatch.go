@@ -0,0 +1,79 @@
+package cryptography
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Dispatcher decrypts values with the decrypter registered for the algorithm
+// named in their envelope. Bare values without an envelope predate the format
+// and are always AES-GCM.
+type Dispatcher struct {
+	decrypters map[string]Decrypter
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		decrypters: map[string]Decrypter{},
+	}
+}
+
+func (d *Dispatcher) Add(algorithm string, decrypter Decrypter) *Dispatcher {
+	d.decrypters[algorithm] = decrypter
+	return d
+}
+
+func (d *Dispatcher) Decrypt(cipherText string) (string, error) {
+	decrypter, err := d.decrypterFor(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	return decrypter.Decrypt(cipherText)
+}
+
+// DecryptFor uses DecryptFor on decrypters that implement KeyAwareDecrypter
+func (d *Dispatcher) DecryptFor(key string, cipherText string) (string, error) {
+	decrypter, err := d.decrypterFor(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	keyAwareDecrypter, ok := decrypter.(KeyAwareDecrypter)
+	if ok {
+		return keyAwareDecrypter.DecryptFor(key, cipherText)
+	}
+
+	return decrypter.Decrypt(cipherText)
+}
+
+// DecryptReader decrypts streams with the AES-GCM decrypter, streams written
+// by EncryptWriter are always AES-GCM
+func (d *Dispatcher) DecryptReader(r io.Reader) (io.Reader, error) {
+	streamDecrypter, ok := d.decrypters[AESGCMAlgorithm].(StreamDecrypter)
+	if !ok {
+		return nil, errors.New("encrypted streams need an aesgcm decrypter")
+	}
+
+	return streamDecrypter.DecryptReader(r)
+}
+
+func (d *Dispatcher) decrypterFor(cipherText string) (Decrypter, error) {
+	algorithm := AESGCMAlgorithm
+
+	if IsEnvelope(cipherText) {
+		envelope, err := ParseEnvelope(cipherText)
+		if err != nil {
+			return nil, err
+		}
+		algorithm = envelope.Algorithm
+	}
+
+	decrypter, ok := d.decrypters[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("no decrypter registered for algorithm %s", algorithm)
+	}
+
+	return decrypter, nil
+}
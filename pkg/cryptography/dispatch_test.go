@@ -0,0 +1,67 @@
+package cryptography
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type mockDispatchTestDecrypter struct {
+	Value string
+}
+
+func (m *mockDispatchTestDecrypter) Decrypt(_ string) (string, error) {
+	return m.Value, nil
+}
+
+func TestDispatcher_DispatchesOnEnvelope(t *testing.T) {
+	dispatcher := NewDispatcher().
+		Add(AESGCMAlgorithm, &mockDispatchTestDecrypter{Value: "aesgcm"}).
+		Add("other", &mockDispatchTestDecrypter{Value: "other"})
+
+	tests := map[string]string{
+		"ENC[aesgcm,v1,dGVzdA==]": "aesgcm",
+		"ENC[other,v1,dGVzdA==]":  "other",
+		"dGVzdA==":                "aesgcm",
+	}
+
+	for input, expected := range tests {
+		got, err := dispatcher.Decrypt(input)
+		if err != nil || got != expected {
+			t.Errorf("%s: expected %q, got %q, %v", input, expected, got, err)
+		}
+	}
+
+	_, err := dispatcher.Decrypt("ENC[unknown,v1,dGVzdA==]")
+	if err == nil || !strings.Contains(err.Error(), "no decrypter registered for algorithm unknown") {
+		t.Errorf("expected unknown algorithm error, got: %v", err)
+	}
+}
+
+func TestDispatcher_DecryptReader(t *testing.T) {
+	crypto, err := NewAESGCMCrypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	var cipherText bytes.Buffer
+	writer, _ := crypto.EncryptWriter(&cipherText)
+	writer.Write([]byte("streamed"))
+	writer.Close()
+
+	reader, err := NewDispatcher().Add(AESGCMAlgorithm, crypto).DecryptReader(bytes.NewReader(cipherText.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	plainText, err := io.ReadAll(reader)
+	if err != nil || string(plainText) != "streamed" {
+		t.Errorf("expected %q, got %q, %v", "streamed", plainText, err)
+	}
+
+	_, err = NewDispatcher().DecryptReader(bytes.NewReader(cipherText.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "need an aesgcm decrypter") {
+		t.Errorf("expected missing decrypter error, got: %v", err)
+	}
+}
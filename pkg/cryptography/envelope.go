@@ -0,0 +1,164 @@
+package cryptography
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	envelopePrefix = "ENC["
+	envelopeSuffix = "]"
+)
+
+// Envelope is the self-describing ciphertext format emitted by every algorithm
+// in this package, e.g.
+//
+//	ENC[aesgcm,v1,kid=prod-2026,<base64>]
+//
+// Params hold optional key=value pairs such as the key ID.
+type Envelope struct {
+	Algorithm string
+	Version   string
+	Params    map[string]string
+	Payload   string
+}
+
+// IsEnvelope reports whether value looks like an Envelope
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix) && strings.HasSuffix(value, envelopeSuffix)
+}
+
+func ParseEnvelope(value string) (Envelope, error) {
+	if !IsEnvelope(value) {
+		return Envelope{}, errors.New("value is not an ENC[...] envelope")
+	}
+
+	inner := strings.TrimSuffix(strings.TrimPrefix(value, envelopePrefix), envelopeSuffix)
+	parts := strings.Split(inner, ",")
+	if len(parts) < 3 {
+		return Envelope{}, errors.New("envelope must contain an algorithm, version and payload")
+	}
+
+	envelope := Envelope{
+		Algorithm: strings.TrimSpace(parts[0]),
+		Version:   strings.TrimSpace(parts[1]),
+		Params:    map[string]string{},
+		Payload:   strings.TrimSpace(parts[len(parts)-1]),
+	}
+
+	for _, part := range parts[2 : len(parts)-1] {
+		key, val, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || key == "" {
+			return Envelope{}, fmt.Errorf("malformed envelope parameter: %s", part)
+		}
+		envelope.Params[key] = val
+	}
+
+	if envelope.Algorithm == "" || envelope.Version == "" || envelope.Payload == "" {
+		return Envelope{}, errors.New("envelope must contain an algorithm, version and payload")
+	}
+
+	return envelope, nil
+}
+
+// ValidateKeyID checks that keyID can be recorded in an envelope, key IDs may
+// only hold letters, digits, '.', '_' and '-'
+func ValidateKeyID(keyID string) error {
+	if keyID == "" || !envelopeToken(keyID, "._-") {
+		return fmt.Errorf("invalid key id %q, use letters, digits, '.', '_' and '-'", keyID)
+	}
+
+	return nil
+}
+
+// Validate checks that String produces an envelope ParseEnvelope reads back
+// the same, e.g. a key ID holding ',' or ']' would break the format
+func (e Envelope) Validate() error {
+	if e.Algorithm == "" || !envelopeToken(e.Algorithm, "._-") {
+		return fmt.Errorf("invalid envelope algorithm %q", e.Algorithm)
+	}
+	if e.Version == "" || !envelopeToken(e.Version, "._-") {
+		return fmt.Errorf("invalid envelope version %q", e.Version)
+	}
+
+	for key, val := range e.Params {
+		if key == "" || !envelopeToken(key, "._-") {
+			return fmt.Errorf("invalid envelope parameter name %q", key)
+		}
+		// Values hold IDs or unpadded base64
+		if !envelopeToken(val, "._-+/") {
+			return fmt.Errorf("invalid value %q for envelope parameter %s", val, key)
+		}
+	}
+
+	if e.Payload == "" || !envelopeToken(e.Payload, "+/=_-") {
+		return errors.New("invalid envelope payload, expected base64")
+	}
+
+	return nil
+}
+
+// envelopeToken reports whether value only holds letters, digits and extra
+func envelopeToken(value string, extra string) bool {
+	for _, r := range value {
+		isAlphaNumeric := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlphaNumeric && !strings.ContainsRune(extra, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String formats the envelope, params are sorted so output is stable. It
+// panics when Validate refuses the envelope, the output could not be parsed
+// back and the value would be lost.
+func (e Envelope) String() string {
+	formatted, err := e.encode()
+	if err != nil {
+		panic("cryptography: " + err.Error())
+	}
+
+	return formatted
+}
+
+// encode is String for encrypters, which return the error instead
+func (e Envelope) encode() (string, error) {
+	err := e.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	parts := []string{e.Algorithm, e.Version}
+
+	keys := make([]string, 0, len(e.Params))
+	for key := range e.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parts = append(parts, key+"="+e.Params[key])
+	}
+
+	parts = append(parts, e.Payload)
+	return envelopePrefix + strings.Join(parts, ",") + envelopeSuffix, nil
+}
+
+// boundAdditionalData returns the additional data to authenticate for an
+// envelope marked aad=key, or nil for values that aren't bound to a key
+func boundAdditionalData(envelope Envelope, key []byte) ([]byte, error) {
+	switch envelope.Params["aad"] {
+	case "":
+		return nil, nil
+	case "key":
+		if key == nil {
+			return nil, errors.New("value is bound to a config key, decrypt it with DecryptFor")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported aad mode %s", envelope.Params["aad"])
+	}
+}
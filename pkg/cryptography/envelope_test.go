@@ -0,0 +1,73 @@
+package cryptography
+
+import (
+	"testing"
+)
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	envelope := Envelope{
+		Algorithm: "aesgcm",
+		Version:   "v1",
+		Params:    map[string]string{"kid": "prod-2026", "aad": "key"},
+		Payload:   "dGVzdA==",
+	}
+
+	formatted := envelope.String()
+	if formatted != "ENC[aesgcm,v1,aad=key,kid=prod-2026,dGVzdA==]" {
+		t.Fatalf("unexpected envelope format: %s", formatted)
+	}
+
+	parsed, err := ParseEnvelope(formatted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.Algorithm != "aesgcm" || parsed.Version != "v1" || parsed.Payload != "dGVzdA==" ||
+		parsed.Params["kid"] != "prod-2026" || parsed.Params["aad"] != "key" {
+		t.Errorf("unexpected parsed envelope: %+v", parsed)
+	}
+}
+
+func TestParseEnvelope_Invalid(t *testing.T) {
+	inputs := []string{
+		"dGVzdA==",
+		"ENC[aesgcm,v1]",
+		"ENC[aesgcm,v1,nokeyvalue,dGVzdA==]",
+		"ENC[,v1,dGVzdA==]",
+		"ENC[aesgcm,v1,dGVzdA==",
+	}
+
+	for _, input := range inputs {
+		_, err := ParseEnvelope(input)
+		if err == nil {
+			t.Errorf("%s: expected error, got none", input)
+		}
+	}
+}
+
+func TestEnvelope_RefusesValuesThatBreakTheFormat(t *testing.T) {
+	for _, keyID := range []string{"prod,2026", "prod]", "kid=2026", "prod 2026", ""} {
+		if ValidateKeyID(keyID) == nil {
+			t.Errorf("%q: expected key id error, got none", keyID)
+		}
+	}
+
+	envelope := Envelope{
+		Algorithm: "aesgcm",
+		Version:   "v1",
+		Params:    map[string]string{"kid": "prod,2026"},
+		Payload:   "dGVzdA==",
+	}
+
+	_, err := envelope.encode()
+	if err == nil {
+		t.Errorf("expected encode error, got none")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected String to panic")
+		}
+	}()
+	_ = envelope.String()
+}
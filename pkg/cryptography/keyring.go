@@ -0,0 +1,220 @@
+package cryptography
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// StaleValue is a value that was decrypted with a key other than the active
+// one and should be re-encrypted
+type StaleValue struct {
+	KeyID      string
+	CipherText string
+	Key        string // The config key, only known when decrypted with DecryptFor
+}
+
+// Keyring holds multiple named keys to support key rotation. It encrypts with
+// the active key and decrypts with the key named by the envelope's kid, or
+// tries every key in the order they were added for values without one.
+type Keyring struct {
+	mu     sync.Mutex
+	keys   map[string]CryptoAlgorithm
+	order  []string
+	active string
+	stale  []StaleValue
+	seen   map[string]bool
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: map[string]CryptoAlgorithm{},
+		seen: map[string]bool{},
+	}
+}
+
+// Add registers algorithm under keyID, the first key added becomes active. It
+// panics when ValidateKeyID refuses keyID.
+func (k *Keyring) Add(keyID string, algorithm CryptoAlgorithm) *Keyring {
+	err := ValidateKeyID(keyID)
+	if err != nil {
+		panic("cryptography: " + err.Error())
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.keys[keyID]; !exists {
+		k.order = append(k.order, keyID)
+	}
+	k.keys[keyID] = algorithm
+
+	if k.active == "" {
+		k.active = keyID
+	}
+
+	return k
+}
+
+// AddAESGCMKey registers a 32 byte AES-GCM key under keyID
+func (k *Keyring) AddAESGCMKey(keyID string, key string) error {
+	err := ValidateKeyID(keyID)
+	if err != nil {
+		return err
+	}
+
+	algorithm, err := NewAESGCMCrypto(key)
+	if err != nil {
+		return err
+	}
+
+	k.Add(keyID, algorithm.WithKeyID(keyID))
+	return nil
+}
+
+// SetActive selects the key used for encryption, every other key is retired
+func (k *Keyring) SetActive(keyID string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[keyID]; !ok {
+		return fmt.Errorf("unknown key id %s", keyID)
+	}
+
+	k.active = keyID
+	return nil
+}
+
+func (k *Keyring) Encrypt(plainText string) (string, error) {
+	return k.encrypt(func(algorithm CryptoAlgorithm) (string, error) {
+		return algorithm.Encrypt(plainText)
+	})
+}
+
+// EncryptFor binds the value to key, the active key must implement
+// KeyAwareEncrypter
+func (k *Keyring) EncryptFor(key string, plainText string) (string, error) {
+	return k.encrypt(func(algorithm CryptoAlgorithm) (string, error) {
+		keyAwareEncrypter, ok := algorithm.(KeyAwareEncrypter)
+		if !ok {
+			return "", errors.New("active key does not support binding values to a config key")
+		}
+		return keyAwareEncrypter.EncryptFor(key, plainText)
+	})
+}
+
+func (k *Keyring) Decrypt(cipherText string) (string, error) {
+	return k.decrypt("", cipherText, func(algorithm CryptoAlgorithm) (string, error) {
+		return algorithm.Decrypt(cipherText)
+	})
+}
+
+// DecryptFor decrypts with DecryptFor on keys that implement
+// KeyAwareDecrypter, and Decrypt on the others
+func (k *Keyring) DecryptFor(key string, cipherText string) (string, error) {
+	return k.decrypt(key, cipherText, func(algorithm CryptoAlgorithm) (string, error) {
+		keyAwareDecrypter, ok := algorithm.(KeyAwareDecrypter)
+		if ok {
+			return keyAwareDecrypter.DecryptFor(key, cipherText)
+		}
+		return algorithm.Decrypt(cipherText)
+	})
+}
+
+func (k *Keyring) encrypt(encrypt func(CryptoAlgorithm) (string, error)) (string, error) {
+	k.mu.Lock()
+	keyID := k.active
+	algorithm := k.keys[keyID]
+	k.mu.Unlock()
+
+	if algorithm == nil {
+		return "", errors.New("keyring has no keys")
+	}
+
+	cipherText, err := encrypt(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", fmt.Errorf("key %s did not produce an envelope: %w", keyID, err)
+	}
+
+	envelope.Params["kid"] = keyID
+	return envelope.encode()
+}
+
+func (k *Keyring) decrypt(key string, cipherText string, decrypt func(CryptoAlgorithm) (string, error)) (string, error) {
+	keyID := ""
+	if IsEnvelope(cipherText) {
+		envelope, err := ParseEnvelope(cipherText)
+		if err != nil {
+			return "", err
+		}
+		keyID = envelope.Params["kid"]
+	}
+
+	if keyID != "" {
+		algorithm, ok := k.key(keyID)
+		if !ok {
+			return "", fmt.Errorf("unknown key id %s", keyID)
+		}
+
+		plainText, err := decrypt(algorithm)
+		if err != nil {
+			return "", err
+		}
+
+		k.recordUse(keyID, key, cipherText)
+		return plainText, nil
+	}
+
+	// Legacy value without a key id, try every key
+	for _, candidateID := range k.keyIDs() {
+		algorithm, _ := k.key(candidateID)
+
+		plainText, err := decrypt(algorithm)
+		if err == nil {
+			k.recordUse(candidateID, key, cipherText)
+			return plainText, nil
+		}
+	}
+
+	return "", errors.New("no key in the keyring could decrypt the value")
+}
+
+// StaleValues returns every value decrypted with a retired key so far
+func (k *Keyring) StaleValues() []StaleValue {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return append([]StaleValue{}, k.stale...)
+}
+
+func (k *Keyring) key(keyID string) (CryptoAlgorithm, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	algorithm, ok := k.keys[keyID]
+	return algorithm, ok
+}
+
+func (k *Keyring) keyIDs() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return append([]string{}, k.order...)
+}
+
+func (k *Keyring) recordUse(keyID string, key string, cipherText string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if keyID == k.active || k.seen[cipherText] {
+		return
+	}
+
+	k.seen[cipherText] = true
+	k.stale = append(k.stale, StaleValue{KeyID: keyID, CipherText: cipherText, Key: key})
+}
@@ -0,0 +1,134 @@
+package cryptography
+
+import (
+	"strings"
+	"testing"
+)
+
+const testRotatedKey string = "abcdefghijklmnopqrstuvwxyz123456"
+
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+
+	keyring := NewKeyring()
+	err := keyring.AddAESGCMKey("2025", testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	err = keyring.AddAESGCMKey("2026", testRotatedKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	return keyring
+}
+
+func TestKeyring_EncryptsWithActiveKey(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	err := keyring.SetActive("2026")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := keyring.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	envelope, err := ParseEnvelope(encrypted)
+	if err != nil || envelope.Params["kid"] != "2026" {
+		t.Fatalf("expected kid 2026 in %s, got %v", encrypted, err)
+	}
+
+	decrypted, err := keyring.Decrypt(encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+
+	if len(keyring.StaleValues()) != 0 {
+		t.Errorf("expected no stale values, got %v", keyring.StaleValues())
+	}
+}
+
+func TestKeyring_ReportsRetiredKeyUse(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	oldKey, _ := NewAESGCMCrypto(testKey)
+	withKeyID, _ := oldKey.WithKeyID("2025").Encrypt("with-kid")
+	withoutKeyID, _ := NewAESGCMCrypto(testRotatedKey)
+	legacy, _ := withoutKeyID.Encrypt("legacy")
+
+	err := keyring.SetActive("2026")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	for cipherText, expected := range map[string]string{withKeyID: "with-kid", legacy: "legacy"} {
+		decrypted, err := keyring.Decrypt(cipherText)
+		if err != nil || decrypted != expected {
+			t.Errorf("expected %q, got %q, %v", expected, decrypted, err)
+		}
+	}
+
+	// Decrypting the same value again should not report it twice
+	_, _ = keyring.Decrypt(withKeyID)
+
+	stale := keyring.StaleValues()
+	if len(stale) != 1 || stale[0].KeyID != "2025" || stale[0].CipherText != withKeyID {
+		t.Errorf("expected only the 2025 value to be stale, got %v", stale)
+	}
+}
+
+func TestKeyring_Errors(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	err := keyring.SetActive("missing")
+	if err == nil || !strings.Contains(err.Error(), "unknown key id missing") {
+		t.Errorf("expected unknown key id error, got: %v", err)
+	}
+
+	_, err = keyring.Decrypt("ENC[aesgcm,v1,kid=missing,dGVzdA==]")
+	if err == nil || !strings.Contains(err.Error(), "unknown key id missing") {
+		t.Errorf("expected unknown key id error, got: %v", err)
+	}
+
+	_, err = keyring.Decrypt("dGVzdGluZ3Rlc3Rpbmd0ZXN0aW5ndGVzdGluZw==")
+	if err == nil || !strings.Contains(err.Error(), "no key in the keyring could decrypt the value") {
+		t.Errorf("expected no key error, got: %v", err)
+	}
+
+	_, err = NewKeyring().Encrypt("value")
+	if err == nil || !strings.Contains(err.Error(), "keyring has no keys") {
+		t.Errorf("expected no keys error, got: %v", err)
+	}
+
+	err = NewKeyring().AddAESGCMKey("prod,2026", testKey)
+	if err == nil || !strings.Contains(err.Error(), "invalid key id") {
+		t.Errorf("expected invalid key id error, got: %v", err)
+	}
+}
+
+func TestKeyring_BoundToKey(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	encrypted, err := keyring.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	err = keyring.SetActive("2026")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	decrypted, err := keyring.DecryptFor("DB_PASSWORD", encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Fatalf("expected bound value to decrypt, got %q, %v", decrypted, err)
+	}
+
+	stale := keyring.StaleValues()
+	if len(stale) != 1 || stale[0].Key != "DB_PASSWORD" || stale[0].KeyID != "2025" {
+		t.Errorf("expected DB_PASSWORD to be reported as stale, got %v", stale)
+	}
+}
@@ -0,0 +1,97 @@
+package cryptography
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// KeyLoader loads key material so keys never have to appear in code or on the
+// command line
+type KeyLoader func() ([]byte, error)
+
+// DecodeKey decodes keys prefixed with "base64:" or "hex:", anything else is
+// used as raw bytes
+func DecodeKey(encoded string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(encoded, "base64:"):
+		key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encoded, "base64:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 key: %w", err)
+		}
+		return key, nil
+	case strings.HasPrefix(encoded, "hex:"):
+		key, err := hex.DecodeString(strings.TrimPrefix(encoded, "hex:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex key: %w", err)
+		}
+		return key, nil
+	default:
+		return []byte(encoded), nil
+	}
+}
+
+func KeyFromString(key string) KeyLoader {
+	return func() ([]byte, error) {
+		return DecodeKey(key)
+	}
+}
+
+func KeyFromEnv(name string) KeyLoader {
+	return func() ([]byte, error) {
+		value, err := ReadKeyEnv(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return DecodeKey(value)
+	}
+}
+
+// KeyFromFile reads a key from path, refusing files that can be accessed by
+// the group or others. A trailing newline is ignored.
+func KeyFromFile(path string) KeyLoader {
+	return func() ([]byte, error) {
+		value, err := ReadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return DecodeKey(value)
+	}
+}
+
+// ReadKeyEnv returns the key text of the environment variable name without
+// decoding it, for algorithm factories that decode keys themselves
+func ReadKeyEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("key environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+// ReadKeyFile returns the key text of path without decoding it, with the same
+// checks as KeyFromFile, for algorithm factories that decode keys themselves
+func ReadKeyFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read key file: %w", err)
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("key file %s is accessible by group or others (mode %s), restrict it with chmod 600", path, info.Mode().Perm())
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read key file: %w", err)
+	}
+
+	value := strings.TrimSuffix(string(content), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
@@ -0,0 +1,98 @@
+package cryptography
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKeysTestFile(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key")
+	err := os.WriteFile(path, []byte(content), perm)
+	if err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	err = os.Chmod(path, perm)
+	if err != nil {
+		t.Fatalf("failed to chmod key file: %v", err)
+	}
+
+	return path
+}
+
+func TestDecodeKey(t *testing.T) {
+	tests := map[string][]byte{
+		"raw-key":              []byte("raw-key"),
+		"base64:AAECAw==":      {0, 1, 2, 3},
+		"hex:00010203":         {0, 1, 2, 3},
+		"base64-looking-raw==": []byte("base64-looking-raw=="),
+	}
+
+	for input, expected := range tests {
+		got, err := DecodeKey(input)
+		if err != nil || !bytes.Equal(got, expected) {
+			t.Errorf("%s: expected %v, got %v, %v", input, expected, got, err)
+		}
+	}
+
+	for _, input := range []string{"base64:not base64", "hex:zz"} {
+		_, err := DecodeKey(input)
+		if err == nil {
+			t.Errorf("%s: expected decode error", input)
+		}
+	}
+}
+
+func TestKeyFromEnv(t *testing.T) {
+	t.Setenv("CONFIGPROVIDER_TEST_KEY", "hex:00010203")
+
+	key, err := KeyFromEnv("CONFIGPROVIDER_TEST_KEY")()
+	if err != nil || !bytes.Equal(key, []byte{0, 1, 2, 3}) {
+		t.Errorf("expected decoded key, got %v, %v", key, err)
+	}
+
+	_, err = KeyFromEnv("CONFIGPROVIDER_TEST_UNSET")()
+	if err == nil || !strings.Contains(err.Error(), "is not set") {
+		t.Errorf("expected unset error, got: %v", err)
+	}
+}
+
+func TestKeyFromFile(t *testing.T) {
+	path := writeKeysTestFile(t, testKey+"\n", 0600)
+
+	key, err := KeyFromFile(path)()
+	if err != nil || string(key) != testKey {
+		t.Errorf("expected key from file, got %q, %v", key, err)
+	}
+
+	path = writeKeysTestFile(t, testKey, 0644)
+	_, err = KeyFromFile(path)()
+	if err == nil || !strings.Contains(err.Error(), "accessible by group or others") {
+		t.Errorf("expected permission error, got: %v", err)
+	}
+
+	_, err = KeyFromFile("nonexistent/key")()
+	if err == nil {
+		t.Errorf("expected missing file error")
+	}
+}
+
+func TestReadKeyFile_KeepsEncoding(t *testing.T) {
+	path := writeKeysTestFile(t, "base64:dGVzdA==\n", 0600)
+
+	value, err := ReadKeyFile(path)
+	if err != nil || value != "base64:dGVzdA==" {
+		t.Errorf("expected undecoded key text, got %q, %v", value, err)
+	}
+
+	t.Setenv("KEYS_TEST_TEXT", "hex:74657374")
+	value, err = ReadKeyEnv("KEYS_TEST_TEXT")
+	if err != nil || value != "hex:74657374" {
+		t.Errorf("expected undecoded key text, got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,189 @@
+package cryptography
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+const (
+	KMSAlgorithm = "kms"
+	kmsVersion   = "v1"
+
+	localKMSAdditionalData = "configprovider local kms v1"
+)
+
+// KeyWrapper wraps and unwraps data keys, typically by calling a key
+// management service so the master key never leaves it
+type KeyWrapper interface {
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(wrappedKey []byte) ([]byte, error)
+}
+
+// EnvelopeCrypto encrypts every value with a fresh AES-256-GCM data key and
+// stores the data key, wrapped by a KeyWrapper, next to the ciphertext:
+//
+//	ENC[kms,v1,wk=<base64 wrapped key>,<base64>]
+type EnvelopeCrypto struct {
+	wrapper KeyWrapper
+}
+
+func NewEnvelopeCrypto(wrapper KeyWrapper) (*EnvelopeCrypto, error) {
+	if wrapper == nil {
+		return nil, errors.New("EnvelopeCrypto: a key wrapper is required")
+	}
+
+	return &EnvelopeCrypto{wrapper: wrapper}, nil
+}
+
+func (c *EnvelopeCrypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *EnvelopeCrypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *EnvelopeCrypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *EnvelopeCrypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *EnvelopeCrypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := c.wrapper.Wrap(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	sealed, err := sealAESGCM(dataKey, []byte(plainText), additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := Envelope{
+		Algorithm: KMSAlgorithm,
+		Version:   kmsVersion,
+		Params: map[string]string{
+			"wk": base64.RawStdEncoding.EncodeToString(wrappedKey),
+		},
+		Payload: base64.StdEncoding.EncodeToString(sealed),
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *EnvelopeCrypto) decrypt(cipherText string, key []byte) (string, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if envelope.Algorithm != KMSAlgorithm {
+		return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, KMSAlgorithm)
+	}
+	if envelope.Version != kmsVersion {
+		return "", fmt.Errorf("unsupported %s version %s", KMSAlgorithm, envelope.Version)
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(envelope.Params["wk"])
+	if err != nil || len(wrappedKey) == 0 {
+		return "", errors.New("invalid or missing wrapped data key")
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	dataKey, err := c.wrapper.Unwrap(wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plainText, err := openAESGCM(dataKey, cipherData, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// LocalKMS is a KeyWrapper backed by a master key in a local file. It stands
+// in for a real key management service during development and tests.
+type LocalKMS struct {
+	masterKey []byte
+}
+
+// NewLocalKMS loads the master key from path, create it first with
+// GenerateLocalKMSKey. A missing file is an error so a mistyped path never
+// silently gets a new key.
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	masterKey, err := KeyFromFile(path)()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("local kms master key %s does not exist, create it with GenerateLocalKMSKey", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("local kms master key must be exactly 32 bytes, got %d", len(masterKey))
+	}
+
+	return &LocalKMS{masterKey: masterKey}, nil
+}
+
+func (k *LocalKMS) Wrap(dataKey []byte) ([]byte, error) {
+	return sealAESGCM(k.masterKey, dataKey, []byte(localKMSAdditionalData))
+}
+
+func (k *LocalKMS) Unwrap(wrappedKey []byte) ([]byte, error) {
+	return openAESGCM(k.masterKey, wrappedKey, []byte(localKMSAdditionalData))
+}
+
+// GenerateLocalKMSKey writes a new master key for NewLocalKMS to path, it
+// refuses to overwrite an existing file
+func GenerateLocalKMSKey(path string) error {
+	masterKey := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, masterKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate master key: %w", err)
+	}
+
+	encoded := "base64:" + base64.StdEncoding.EncodeToString(masterKey) + "\n"
+
+	// O_EXCL so an existing key is never overwritten
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create master key file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(encoded)
+	if err != nil {
+		return fmt.Errorf("failed to write master key file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package cryptography
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type mockKMSTestWrapper struct {
+	Wraps   int
+	Unwraps int
+	Err     error
+}
+
+func (m *mockKMSTestWrapper) Wrap(dataKey []byte) ([]byte, error) {
+	m.Wraps++
+	return append([]byte("wrapped:"), dataKey...), m.Err
+}
+
+func (m *mockKMSTestWrapper) Unwrap(wrappedKey []byte) ([]byte, error) {
+	m.Unwraps++
+	return []byte(strings.TrimPrefix(string(wrappedKey), "wrapped:")), m.Err
+}
+
+func TestEnvelopeCrypto_EncryptionAndDecryption(t *testing.T) {
+	wrapper := &mockKMSTestWrapper{}
+	crypto, err := NewEnvelopeCrypto(wrapper)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encryption1, err1 := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	encryption2, err2 := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err1 != nil || err2 != nil {
+		t.Fatalf("encryption failed: %v, %v", err1, err2)
+	}
+
+	envelope1, _ := ParseEnvelope(encryption1)
+	envelope2, _ := ParseEnvelope(encryption2)
+	if envelope1.Algorithm != KMSAlgorithm || envelope1.Params["wk"] == "" || envelope1.Params["wk"] == envelope2.Params["wk"] {
+		t.Errorf("expected a distinct wrapped data key per value, got %s and %s", encryption1, encryption2)
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encryption1)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+
+	if wrapper.Wraps != 2 || wrapper.Unwraps != 1 {
+		t.Errorf("expected 2 wraps and 1 unwrap, got %d and %d", wrapper.Wraps, wrapper.Unwraps)
+	}
+}
+
+func TestEnvelopeCrypto_WrapperErrors(t *testing.T) {
+	crypto, err := NewEnvelopeCrypto(&mockKMSTestWrapper{Err: errors.New("kms unavailable")})
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err = crypto.Encrypt("super-secret-value")
+	if err == nil || !strings.Contains(err.Error(), "failed to wrap data key: kms unavailable") {
+		t.Errorf("expected wrap error, got: %v", err)
+	}
+
+	_, err = crypto.Decrypt("ENC[kms,v1,wk=d3JhcHBlZA,dGVzdA==]")
+	if err == nil || !strings.Contains(err.Error(), "failed to unwrap data key: kms unavailable") {
+		t.Errorf("expected unwrap error, got: %v", err)
+	}
+
+	_, err = crypto.Decrypt("ENC[kms,v1,dGVzdA==]")
+	if err == nil || !strings.Contains(err.Error(), "invalid or missing wrapped data key") {
+		t.Errorf("expected missing wrapped key error, got: %v", err)
+	}
+
+	_, err = NewEnvelopeCrypto(nil)
+	if err == nil {
+		t.Errorf("expected error for missing wrapper")
+	}
+}
+
+func newTestLocalKMSKey(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "kms.key")
+	err := GenerateLocalKMSKey(path)
+	if err != nil {
+		t.Fatalf("failed to generate master key: %v", err)
+	}
+
+	return path
+}
+
+func TestLocalKMS_ReusesMasterKeyFile(t *testing.T) {
+	path := newTestLocalKMSKey(t)
+
+	kms, err := NewLocalKMS(path)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	crypto, _ := NewEnvelopeCrypto(kms)
+	encrypted, err := crypto.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	reloadedKMS, err := NewLocalKMS(path)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	reloaded, _ := NewEnvelopeCrypto(reloadedKMS)
+	decrypted, err := reloaded.Decrypt(encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip with the same master key, got %q, %v", decrypted, err)
+	}
+
+	otherKMS, err := NewLocalKMS(newTestLocalKMSKey(t))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	other, _ := NewEnvelopeCrypto(otherKMS)
+	_, err = other.Decrypt(encrypted)
+	if err == nil || !strings.Contains(err.Error(), "failed to unwrap data key") {
+		t.Errorf("expected unwrap with another master key to fail, got: %v", err)
+	}
+}
+
+func TestLocalKMS_MissingKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.key")
+
+	_, err := NewLocalKMS(path)
+	if err == nil || !strings.Contains(err.Error(), "does not exist, create it with GenerateLocalKMSKey") {
+		t.Errorf("expected missing key error, got: %v", err)
+	}
+
+	_, err = os.Stat(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no key file to be created, got: %v", err)
+	}
+
+	err = GenerateLocalKMSKey(newTestLocalKMSKey(t))
+	if err == nil {
+		t.Error("expected GenerateLocalKMSKey to refuse an existing file")
+	}
+}
@@ -0,0 +1,221 @@
+package cryptography
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	PassphraseAlgorithm = "aesgcm-pbkdf2"
+	passphraseVersion   = "v1"
+
+	// DefaultPBKDF2Iterations follows the OWASP recommendation for
+	// PBKDF2-HMAC-SHA256
+	DefaultPBKDF2Iterations = 600_000
+	// DefaultMaxPBKDF2Iterations bounds the iteration count accepted from an
+	// envelope, so a single committed value can't stall decryption
+	DefaultMaxPBKDF2Iterations = 1_000_000
+	minPBKDF2Iterations        = 1_000
+	pbkdf2SaltSize             = 16
+)
+
+// PassphraseCrypto derives an AES-256 key from a passphrase with
+// PBKDF2-HMAC-SHA256. Every value gets a random salt, which is stored in the
+// envelope together with the iteration count:
+//
+//	ENC[aesgcm-pbkdf2,v1,iter=600000,salt=<base64>,<base64>]
+type PassphraseCrypto struct {
+	passphrase    string
+	iterations    int
+	maxIterations int
+	mu            sync.Mutex
+	derivedKeys   map[string]*derivedKey
+}
+
+// derivedKey is a cache entry, done is closed once key or err is set so
+// concurrent callers for the same salt wait for one derivation
+type derivedKey struct {
+	done chan struct{}
+	key  []byte
+	err  error
+}
+
+func NewPassphraseCrypto(passphrase string) (*PassphraseCrypto, error) {
+	if passphrase == "" {
+		return nil, errors.New("PassphraseCrypto: passphrase must not be empty")
+	}
+
+	return &PassphraseCrypto{
+		passphrase:    passphrase,
+		iterations:    DefaultPBKDF2Iterations,
+		maxIterations: DefaultMaxPBKDF2Iterations,
+		derivedKeys:   map[string]*derivedKey{},
+	}, nil
+}
+
+// WithIterations sets the PBKDF2 iteration count used for encryption,
+// decryption always uses the count stored in the envelope
+func (c *PassphraseCrypto) WithIterations(iterations int) *PassphraseCrypto {
+	c.iterations = iterations
+	return c
+}
+
+// WithMaxIterations sets the highest iteration count accepted, the default
+// is DefaultMaxPBKDF2Iterations
+func (c *PassphraseCrypto) WithMaxIterations(maxIterations int) *PassphraseCrypto {
+	c.maxIterations = maxIterations
+	return c
+}
+
+func (c *PassphraseCrypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *PassphraseCrypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *PassphraseCrypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *PassphraseCrypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *PassphraseCrypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	err := c.validateIterations(c.iterations)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, pbkdf2SaltSize)
+	_, err = io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	// Not cached, the salt is fresh so the key is never derived again
+	key, err := pbkdf2.Key(sha256.New, c.passphrase, salt, c.iterations, 32)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	final, err := sealAESGCM(key, []byte(plainText), additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := Envelope{
+		Algorithm: PassphraseAlgorithm,
+		Version:   passphraseVersion,
+		Params: map[string]string{
+			"iter": strconv.Itoa(c.iterations),
+			"salt": base64.RawStdEncoding.EncodeToString(salt),
+		},
+		Payload: base64.StdEncoding.EncodeToString(final),
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *PassphraseCrypto) decrypt(cipherText string, key []byte) (string, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if envelope.Algorithm != PassphraseAlgorithm {
+		return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, PassphraseAlgorithm)
+	}
+	if envelope.Version != passphraseVersion {
+		return "", fmt.Errorf("unsupported %s version %s", PassphraseAlgorithm, envelope.Version)
+	}
+
+	iterations, err := strconv.Atoi(envelope.Params["iter"])
+	if err != nil {
+		return "", fmt.Errorf("invalid iteration count: %w", err)
+	}
+	err = c.validateIterations(iterations)
+	if err != nil {
+		return "", err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(envelope.Params["salt"])
+	if err != nil || len(salt) == 0 {
+		return "", errors.New("invalid or missing salt")
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	passphraseKey, err := c.deriveKey(salt, iterations)
+	if err != nil {
+		return "", err
+	}
+
+	plainText, err := openAESGCM(passphraseKey, cipherData, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// deriveKey caches derived keys by salt and iteration count so decrypting the
+// same value again, e.g. on reload, doesn't pay the KDF cost twice. The KDF
+// runs outside the lock so values with different salts derive in parallel.
+// Only decryption uses it, encryption never sees the same salt twice.
+func (c *PassphraseCrypto) deriveKey(salt []byte, iterations int) ([]byte, error) {
+	cacheKey := strconv.Itoa(iterations) + ":" + string(salt)
+
+	c.mu.Lock()
+	entry, ok := c.derivedKeys[cacheKey]
+	if !ok {
+		entry = &derivedKey{done: make(chan struct{})}
+		c.derivedKeys[cacheKey] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		<-entry.done
+		return entry.key, entry.err
+	}
+
+	entry.key, entry.err = pbkdf2.Key(sha256.New, c.passphrase, salt, iterations, 32)
+	if entry.err != nil {
+		entry.err = fmt.Errorf("failed to derive key: %w", entry.err)
+
+		c.mu.Lock()
+		delete(c.derivedKeys, cacheKey)
+		c.mu.Unlock()
+	}
+
+	close(entry.done)
+	return entry.key, entry.err
+}
+
+func (c *PassphraseCrypto) validateIterations(iterations int) error {
+	if iterations < minPBKDF2Iterations || iterations > c.maxIterations {
+		return fmt.Errorf("PBKDF2 iterations must be between %d and %d, got %d", minPBKDF2Iterations, c.maxIterations, iterations)
+	}
+
+	return nil
+}
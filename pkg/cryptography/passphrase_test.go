@@ -0,0 +1,203 @@
+package cryptography
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testPassphrase string = "correct horse battery staple"
+
+func newTestPassphraseCrypto(t *testing.T) *PassphraseCrypto {
+	t.Helper()
+
+	crypto, err := NewPassphraseCrypto(testPassphrase)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	return crypto.WithIterations(minPBKDF2Iterations)
+}
+
+func TestPassphrase_EncryptionAndDecryption(t *testing.T) {
+	crypto := newTestPassphraseCrypto(t)
+
+	encrypted, err := crypto.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	envelope, err := ParseEnvelope(encrypted)
+	if err != nil || envelope.Algorithm != PassphraseAlgorithm || envelope.Params["iter"] != "1000" || envelope.Params["salt"] == "" {
+		t.Fatalf("unexpected envelope %s: %v", encrypted, err)
+	}
+
+	// A fresh instance has no cached keys and must derive from the envelope
+	decrypter, err := NewPassphraseCrypto(testPassphrase)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	decrypted, err := decrypter.Decrypt(encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+}
+
+func TestPassphrase_UniqueSaltPerValue(t *testing.T) {
+	crypto := newTestPassphraseCrypto(t)
+
+	encryption1, err1 := crypto.Encrypt("super-secret-value")
+	encryption2, err2 := crypto.Encrypt("super-secret-value")
+	if err1 != nil || err2 != nil {
+		t.Fatalf("encryption failed: %v, %v", err1, err2)
+	}
+
+	envelope1, _ := ParseEnvelope(encryption1)
+	envelope2, _ := ParseEnvelope(encryption2)
+	if envelope1.Params["salt"] == envelope2.Params["salt"] {
+		t.Errorf("expected a different salt per value")
+	}
+}
+
+func TestPassphrase_WrongPassphrase(t *testing.T) {
+	encrypted, err := newTestPassphraseCrypto(t).Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	crypto, err := NewPassphraseCrypto("wrong passphrase")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err = crypto.Decrypt(encrypted)
+	if err == nil || !strings.Contains(err.Error(), "decryption failed") {
+		t.Errorf("expected decryption failure, got: %v", err)
+	}
+}
+
+func TestPassphrase_BoundToKey(t *testing.T) {
+	crypto := newTestPassphraseCrypto(t)
+
+	encrypted, err := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+
+	_, err = crypto.DecryptFor("ADMIN_TOKEN", encrypted)
+	if err == nil {
+		t.Errorf("expected value moved to another key to fail")
+	}
+}
+
+func TestPassphrase_InvalidParameters(t *testing.T) {
+	_, err := NewPassphraseCrypto("")
+	if err == nil {
+		t.Errorf("expected error for empty passphrase")
+	}
+
+	_, err = newTestPassphraseCrypto(t).WithIterations(1).Encrypt("value")
+	if err == nil || !strings.Contains(err.Error(), "PBKDF2 iterations must be between") {
+		t.Errorf("expected iteration count error, got: %v", err)
+	}
+
+	crypto := newTestPassphraseCrypto(t)
+	tests := map[string]string{
+		"ENC[aesgcm-pbkdf2,v1,iter=999999999,salt=c2FsdA,dGVzdA==]": "PBKDF2 iterations must be between",
+		"ENC[aesgcm-pbkdf2,v1,iter=1000,dGVzdA==]":                  "invalid or missing salt",
+		"ENC[aesgcm,v1,dGVzdA==]":                                   "unsupported algorithm aesgcm",
+	}
+
+	for input, expected := range tests {
+		_, err := crypto.Decrypt(input)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected error containing %q, got: %v", input, expected, err)
+		}
+	}
+}
+
+func TestPassphrase_MaxIterations(t *testing.T) {
+	cipherText, err := newTestPassphraseCrypto(t).WithIterations(2000).Encrypt("value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	_, err = newTestPassphraseCrypto(t).WithMaxIterations(1500).Decrypt(cipherText)
+	if err == nil || !strings.Contains(err.Error(), "between 1000 and 1500, got 2000") {
+		t.Errorf("expected iteration cap error, got: %v", err)
+	}
+
+	_, err = newTestPassphraseCrypto(t).WithIterations(DefaultMaxPBKDF2Iterations + 1).Encrypt("value")
+	if err == nil || !strings.Contains(err.Error(), "PBKDF2 iterations must be between") {
+		t.Errorf("expected default cap to apply to encryption, got: %v", err)
+	}
+}
+
+func TestPassphrase_ConcurrentDecryption(t *testing.T) {
+	encrypter := newTestPassphraseCrypto(t)
+
+	cipherTexts := make([]string, 4)
+	for i := range cipherTexts {
+		cipherText, err := encrypter.Encrypt(fmt.Sprintf("value-%d", i%2))
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+		cipherTexts[i] = cipherText
+	}
+
+	// Every value is decrypted by several goroutines at once, sharing the
+	// derivation of its key
+	crypto := newTestPassphraseCrypto(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*len(cipherTexts))
+
+	for range 4 {
+		for i, cipherText := range cipherTexts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				plainText, err := crypto.Decrypt(cipherText)
+				if err == nil && plainText != fmt.Sprintf("value-%d", i%2) {
+					err = fmt.Errorf("unexpected plaintext %q", plainText)
+				}
+				errs <- err
+			}()
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("decryption failed: %v", err)
+		}
+	}
+
+	if len(crypto.derivedKeys) != len(cipherTexts) {
+		t.Errorf("expected %d cached keys, got %d", len(cipherTexts), len(crypto.derivedKeys))
+	}
+}
+
+func TestPassphrase_EncryptionDoesNotCacheKeys(t *testing.T) {
+	crypto := newTestPassphraseCrypto(t)
+
+	for range 3 {
+		_, err := crypto.Encrypt("value")
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+	}
+
+	if len(crypto.derivedKeys) != 0 {
+		t.Errorf("expected no cached keys after encryption, got %d", len(crypto.derivedKeys))
+	}
+}
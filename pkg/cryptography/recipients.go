@@ -0,0 +1,308 @@
+package cryptography
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	MultiRecipientAlgorithm = "x25519-multi"
+	multiRecipientVersion   = "v1"
+)
+
+// MultiRecipientCrypto encrypts a value once with a random data key and wraps
+// that data key for every recipient's X25519 public key, so any single
+// recipient can decrypt it with their own private key. Recipients can be added
+// or removed without changing the encrypted value itself.
+type MultiRecipientCrypto struct {
+	recipients []*ecdh.PublicKey
+	privateKey *ecdh.PrivateKey
+}
+
+type multiRecipientPayload struct {
+	Recipients []recipientStanza `json:"recipients"`
+	Data       []byte            `json:"data"`
+}
+
+// recipientStanza holds the data key wrapped for one recipient
+type recipientStanza struct {
+	ID                 string `json:"id"`
+	EphemeralPublicKey []byte `json:"epk"`
+	WrappedKey         []byte `json:"key"`
+}
+
+// NewMultiRecipientEncrypter encrypts to every public key, it can't decrypt
+func NewMultiRecipientEncrypter(publicKeys ...string) (*MultiRecipientCrypto, error) {
+	if len(publicKeys) == 0 {
+		return nil, errors.New("at least one recipient public key is required")
+	}
+
+	recipients := make([]*ecdh.PublicKey, 0, len(publicKeys))
+	for _, publicKey := range publicKeys {
+		recipient, err := parseX25519PublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	return &MultiRecipientCrypto{recipients: recipients}, nil
+}
+
+// NewMultiRecipientDecrypter decrypts values that were encrypted to the public
+// key of privateKey, and can add recipients to them. Encrypting with it
+// encrypts to that single recipient.
+func NewMultiRecipientDecrypter(privateKey string) (*MultiRecipientCrypto, error) {
+	key, err := decodeX25519Key(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKey, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 private key: %w", err)
+	}
+
+	return &MultiRecipientCrypto{
+		recipients: []*ecdh.PublicKey{parsedKey.PublicKey()},
+		privateKey: parsedKey,
+	}, nil
+}
+
+// RecipientID returns the short fingerprint identifying a public key in the
+// ciphertext
+func RecipientID(publicKey string) (string, error) {
+	recipient, err := parseX25519PublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return recipientID(recipient), nil
+}
+
+func (c *MultiRecipientCrypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *MultiRecipientCrypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *MultiRecipientCrypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *MultiRecipientCrypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+// AddRecipient re-wraps the data key of cipherText for publicKey. The caller
+// must be a recipient already.
+func (c *MultiRecipientCrypto) AddRecipient(cipherText string, publicKey string) (string, error) {
+	recipient, err := parseX25519PublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	envelope, payload, err := parseMultiRecipient(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	for _, stanza := range payload.Recipients {
+		if stanza.ID == recipientID(recipient) {
+			return cipherText, nil
+		}
+	}
+
+	dataKey, err := c.unwrapDataKey(payload)
+	if err != nil {
+		return "", err
+	}
+
+	stanza, err := wrapDataKey(recipient, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	payload.Recipients = append(payload.Recipients, stanza)
+	return formatMultiRecipient(envelope, payload)
+}
+
+// RemoveRecipient drops the wrapped data key of publicKey from cipherText.
+// The removed recipient may still know the data key, rotate the secret itself
+// if that matters.
+func RemoveRecipient(cipherText string, publicKey string) (string, error) {
+	id, err := RecipientID(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	envelope, payload, err := parseMultiRecipient(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	remaining := payload.Recipients[:0]
+	for _, stanza := range payload.Recipients {
+		if stanza.ID != id {
+			remaining = append(remaining, stanza)
+		}
+	}
+
+	if len(remaining) == len(payload.Recipients) {
+		return "", fmt.Errorf("recipient %s not found", id)
+	}
+	if len(remaining) == 0 {
+		return "", errors.New("can't remove the last recipient")
+	}
+
+	payload.Recipients = remaining
+	return formatMultiRecipient(envelope, payload)
+}
+
+func (c *MultiRecipientCrypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	sealed, err := sealAESGCM(dataKey, []byte(plainText), additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	payload := multiRecipientPayload{Data: sealed}
+	for _, recipient := range c.recipients {
+		stanza, err := wrapDataKey(recipient, dataKey)
+		if err != nil {
+			return "", err
+		}
+		payload.Recipients = append(payload.Recipients, stanza)
+	}
+
+	envelope := Envelope{
+		Algorithm: MultiRecipientAlgorithm,
+		Version:   multiRecipientVersion,
+		Params:    map[string]string{},
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return formatMultiRecipient(envelope, payload)
+}
+
+func (c *MultiRecipientCrypto) decrypt(cipherText string, key []byte) (string, error) {
+	envelope, payload, err := parseMultiRecipient(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := c.unwrapDataKey(payload)
+	if err != nil {
+		return "", err
+	}
+
+	plainText, err := openAESGCM(dataKey, payload.Data, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+func (c *MultiRecipientCrypto) unwrapDataKey(payload multiRecipientPayload) ([]byte, error) {
+	if c.privateKey == nil {
+		return nil, errors.New("x25519-multi decryption requires a recipient's private key")
+	}
+
+	id := recipientID(c.privateKey.PublicKey())
+	for _, stanza := range payload.Recipients {
+		if stanza.ID != id {
+			continue
+		}
+
+		wrappingKey, err := x25519Unwrap(c.privateKey, stanza.EphemeralPublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return openAESGCM(wrappingKey, stanza.WrappedKey, nil)
+	}
+
+	return nil, fmt.Errorf("value is not encrypted to recipient %s", id)
+}
+
+func wrapDataKey(recipient *ecdh.PublicKey, dataKey []byte) (recipientStanza, error) {
+	ephemeralPublicKey, wrappingKey, err := x25519Wrap(recipient)
+	if err != nil {
+		return recipientStanza{}, err
+	}
+
+	wrappedKey, err := sealAESGCM(wrappingKey, dataKey, nil)
+	if err != nil {
+		return recipientStanza{}, err
+	}
+
+	return recipientStanza{
+		ID:                 recipientID(recipient),
+		EphemeralPublicKey: ephemeralPublicKey,
+		WrappedKey:         wrappedKey,
+	}, nil
+}
+
+func parseMultiRecipient(cipherText string) (Envelope, multiRecipientPayload, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return Envelope{}, multiRecipientPayload{}, err
+	}
+
+	if envelope.Algorithm != MultiRecipientAlgorithm {
+		return Envelope{}, multiRecipientPayload{}, fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, MultiRecipientAlgorithm)
+	}
+	if envelope.Version != multiRecipientVersion {
+		return Envelope{}, multiRecipientPayload{}, fmt.Errorf("unsupported %s version %s", MultiRecipientAlgorithm, envelope.Version)
+	}
+
+	rawPayload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return Envelope{}, multiRecipientPayload{}, fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	var payload multiRecipientPayload
+	err = json.Unmarshal(rawPayload, &payload)
+	if err != nil {
+		return Envelope{}, multiRecipientPayload{}, fmt.Errorf("malformed x25519-multi payload: %w", err)
+	}
+
+	return envelope, payload, nil
+}
+
+func formatMultiRecipient(envelope Envelope, payload multiRecipientPayload) (string, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode x25519-multi payload: %w", err)
+	}
+
+	envelope.Payload = base64.StdEncoding.EncodeToString(rawPayload)
+	return envelope.encode()
+}
+
+func recipientID(publicKey *ecdh.PublicKey) string {
+	sum := sha256.Sum256(publicKey.Bytes())
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,131 @@
+package cryptography
+
+import (
+	"strings"
+	"testing"
+)
+
+type testKeyPair struct {
+	publicKey  string
+	privateKey string
+}
+
+func generateTestKeyPairs(t *testing.T, count int) []testKeyPair {
+	t.Helper()
+
+	keyPairs := make([]testKeyPair, 0, count)
+	for range count {
+		publicKey, privateKey, err := GenerateX25519KeyPair()
+		if err != nil {
+			t.Fatalf("error: %v", err)
+		}
+		keyPairs = append(keyPairs, testKeyPair{publicKey: publicKey, privateKey: privateKey})
+	}
+
+	return keyPairs
+}
+
+func newTestMultiRecipientDecrypter(t *testing.T, keyPair testKeyPair) *MultiRecipientCrypto {
+	t.Helper()
+
+	decrypter, err := NewMultiRecipientDecrypter(keyPair.privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	return decrypter
+}
+
+func TestMultiRecipient_AnyRecipientCanDecrypt(t *testing.T) {
+	keyPairs := generateTestKeyPairs(t, 3)
+
+	encrypter, err := NewMultiRecipientEncrypter(keyPairs[0].publicKey, keyPairs[1].publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := encrypter.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	for _, keyPair := range keyPairs[:2] {
+		decrypted, err := newTestMultiRecipientDecrypter(t, keyPair).DecryptFor("DB_PASSWORD", encrypted)
+		if err != nil || decrypted != "super-secret-value" {
+			t.Errorf("expected recipient to decrypt, got %q, %v", decrypted, err)
+		}
+	}
+
+	_, err = newTestMultiRecipientDecrypter(t, keyPairs[2]).DecryptFor("DB_PASSWORD", encrypted)
+	if err == nil || !strings.Contains(err.Error(), "value is not encrypted to recipient") {
+		t.Errorf("expected non-recipient to fail, got: %v", err)
+	}
+
+	_, err = encrypter.DecryptFor("DB_PASSWORD", encrypted)
+	if err == nil || !strings.Contains(err.Error(), "requires a recipient's private key") {
+		t.Errorf("expected encrypter without private key to fail, got: %v", err)
+	}
+}
+
+func TestMultiRecipient_AddAndRemoveRecipient(t *testing.T) {
+	keyPairs := generateTestKeyPairs(t, 2)
+
+	encrypter, err := NewMultiRecipientEncrypter(keyPairs[0].publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := encrypter.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	owner := newTestMultiRecipientDecrypter(t, keyPairs[0])
+	newRecipient := newTestMultiRecipientDecrypter(t, keyPairs[1])
+
+	added, err := owner.AddRecipient(encrypted, keyPairs[1].publicKey)
+	if err != nil {
+		t.Fatalf("add recipient failed: %v", err)
+	}
+
+	_, originalPayload, _ := parseMultiRecipient(encrypted)
+	_, addedPayload, _ := parseMultiRecipient(added)
+	if string(originalPayload.Data) != string(addedPayload.Data) {
+		t.Errorf("expected the encrypted data to be unchanged when adding a recipient")
+	}
+
+	decrypted, err := newRecipient.Decrypt(added)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected added recipient to decrypt, got %q, %v", decrypted, err)
+	}
+
+	removed, err := RemoveRecipient(added, keyPairs[0].publicKey)
+	if err != nil {
+		t.Fatalf("remove recipient failed: %v", err)
+	}
+
+	_, err = owner.Decrypt(removed)
+	if err == nil {
+		t.Errorf("expected removed recipient to fail")
+	}
+
+	decrypted, err = newRecipient.Decrypt(removed)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected remaining recipient to decrypt, got %q, %v", decrypted, err)
+	}
+
+	_, err = RemoveRecipient(removed, keyPairs[1].publicKey)
+	if err == nil || !strings.Contains(err.Error(), "can't remove the last recipient") {
+		t.Errorf("expected last recipient error, got: %v", err)
+	}
+
+	_, err = RemoveRecipient(removed, keyPairs[0].publicKey)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected recipient not found error, got: %v", err)
+	}
+
+	_, err = newRecipient.AddRecipient(encrypted, keyPairs[1].publicKey)
+	if err == nil || !strings.Contains(err.Error(), "value is not encrypted to recipient") {
+		t.Errorf("expected non-recipient to be unable to add recipients, got: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package cryptography
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AlgorithmOptions are the optional settings passed to an AlgorithmFactory,
+// factories ignore the options that don't apply to them
+type AlgorithmOptions struct {
+	// KeyID is recorded in the envelope of encrypted values
+	KeyID string
+	// Iterations is the KDF cost for passphrase based algorithms, 0 uses the
+	// default
+	Iterations int
+	// PublicKeys creates an encrypt-only algorithm for public key algorithms
+	PublicKeys []string
+}
+
+// AlgorithmFactory creates an algorithm from a key. What the key is depends on
+// the algorithm, e.g. a raw or encoded symmetric key, a passphrase, a private
+// key or a path.
+type AlgorithmFactory func(key string, opts AlgorithmOptions) (CryptoAlgorithm, error)
+
+// Algorithm is a registered algorithm
+type Algorithm struct {
+	Name        string
+	Description string
+	New         AlgorithmFactory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Algorithm{}
+)
+
+// Register makes an algorithm available by name to Lookup, the provider's
+// WithAlgorithm and lockbox. It panics if name is empty, factory is nil or
+// the name is already registered.
+func Register(name string, description string, factory AlgorithmFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("cryptography: Register algorithm name is empty")
+	}
+	if factory == nil {
+		panic("cryptography: Register factory is nil for " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("cryptography: Register called twice for " + name)
+	}
+
+	registry[name] = Algorithm{Name: name, Description: description, New: factory}
+}
+
+// Lookup returns the algorithm registered under name
+func Lookup(name string) (Algorithm, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	algorithm, ok := registry[name]
+	return algorithm, ok
+}
+
+// List returns every registered algorithm sorted by name
+func List() []Algorithm {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	algorithms := make([]Algorithm, 0, len(registry))
+	for _, algorithm := range registry {
+		algorithms = append(algorithms, algorithm)
+	}
+
+	sort.Slice(algorithms, func(i, j int) bool {
+		return algorithms[i].Name < algorithms[j].Name
+	})
+
+	return algorithms
+}
+
+// NewAlgorithm creates the algorithm registered under name, opts.KeyID is
+// checked with ValidateKeyID first
+func NewAlgorithm(name string, key string, opts AlgorithmOptions) (CryptoAlgorithm, error) {
+	algorithm, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm, %v", name)
+	}
+
+	if opts.KeyID != "" {
+		err := ValidateKeyID(opts.KeyID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return algorithm.New(key, opts)
+}
+
+func init() {
+	Register(AESGCMAlgorithm, "AES-GCM encryption with 256-bit key (raw, base64: or hex:)", newAESGCMAlgorithm)
+	Register(XChaCha20Poly1305Algorithm, "XChaCha20-Poly1305 encryption with 256-bit key (raw, base64: or hex:)", newXChaCha20Poly1305Algorithm)
+	Register(AESSIVAlgorithm, "Deterministic AES-SIV encryption with 256, 384 or 512-bit key (raw, base64: or hex:)", newAESSIVAlgorithm)
+	Register(PassphraseAlgorithm, "AES-GCM encryption with a PBKDF2-derived key (use --passphrase)", newPassphraseAlgorithm)
+	Register(X25519Algorithm, "X25519 + AES-GCM public key encryption (key is the private key from keygen, use --pubkey to encrypt)", newX25519Algorithm)
+	Register(MultiRecipientAlgorithm, "X25519 + AES-GCM encryption to multiple recipients (key is the private key from keygen, use --pubkey=key1,key2)", newMultiRecipientAlgorithm)
+	Register(KMSAlgorithm, "AES-GCM with data keys wrapped by a local file KMS (key is the KMS file path, create it with keygen --c=kms --out=<path>)", newLocalKMSAlgorithm)
+}
+
+func newAESGCMAlgorithm(key string, opts AlgorithmOptions) (CryptoAlgorithm, error) {
+	decodedKey, err := DecodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, err := NewAESGCMCrypto(string(decodedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return algorithm.WithKeyID(opts.KeyID), nil
+}
+
+func newXChaCha20Poly1305Algorithm(key string, _ AlgorithmOptions) (CryptoAlgorithm, error) {
+	decodedKey, err := DecodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewXChaCha20Poly1305Crypto(string(decodedKey))
+}
+
+func newAESSIVAlgorithm(key string, _ AlgorithmOptions) (CryptoAlgorithm, error) {
+	decodedKey, err := DecodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAESSIVCrypto(string(decodedKey))
+}
+
+func newPassphraseAlgorithm(key string, opts AlgorithmOptions) (CryptoAlgorithm, error) {
+	algorithm, err := NewPassphraseCrypto(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The cap isn't raised, values above it could not be decrypted by
+	// WithPassphraseDecrypter or WithAlgorithm
+	if opts.Iterations != 0 {
+		err = algorithm.validateIterations(opts.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		algorithm = algorithm.WithIterations(opts.Iterations)
+	}
+
+	return algorithm, nil
+}
+
+func newX25519Algorithm(key string, opts AlgorithmOptions) (CryptoAlgorithm, error) {
+	switch len(opts.PublicKeys) {
+	case 0:
+		return NewX25519Crypto(key)
+	case 1:
+		return NewX25519Encrypter(opts.PublicKeys[0])
+	default:
+		return nil, fmt.Errorf("%s encrypts to a single public key, use %s for multiple recipients", X25519Algorithm, MultiRecipientAlgorithm)
+	}
+}
+
+func newMultiRecipientAlgorithm(key string, opts AlgorithmOptions) (CryptoAlgorithm, error) {
+	if len(opts.PublicKeys) > 0 {
+		return NewMultiRecipientEncrypter(opts.PublicKeys...)
+	}
+
+	return NewMultiRecipientDecrypter(key)
+}
+
+func newLocalKMSAlgorithm(key string, _ AlgorithmOptions) (CryptoAlgorithm, error) {
+	kms, err := NewLocalKMS(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEnvelopeCrypto(kms)
+}
@@ -0,0 +1,138 @@
+package cryptography
+
+import (
+	"strings"
+	"testing"
+)
+
+type registryTestAlgorithm struct {
+	key string
+}
+
+func (a registryTestAlgorithm) Encrypt(plainText string) (string, error) {
+	return a.key + ":" + plainText, nil
+}
+
+func (a registryTestAlgorithm) Decrypt(cipherText string) (string, error) {
+	return strings.TrimPrefix(cipherText, a.key+":"), nil
+}
+
+func TestRegistry_BuiltinsRoundTrip(t *testing.T) {
+	publicKey, privateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		encryptKey string
+		decryptKey string
+		opts       AlgorithmOptions
+	}{
+		{AESGCMAlgorithm, testKey, testKey, AlgorithmOptions{KeyID: "prod"}},
+		{XChaCha20Poly1305Algorithm, testKey, testKey, AlgorithmOptions{}},
+		{AESSIVAlgorithm, testKey, testKey, AlgorithmOptions{}},
+		{PassphraseAlgorithm, "passphrase", "passphrase", AlgorithmOptions{Iterations: 1000}},
+		{X25519Algorithm, "", privateKey, AlgorithmOptions{PublicKeys: []string{publicKey}}},
+		{MultiRecipientAlgorithm, "", privateKey, AlgorithmOptions{PublicKeys: []string{publicKey}}},
+		{KMSAlgorithm, newTestLocalKMSKey(t), "", AlgorithmOptions{}},
+	}
+
+	for _, test := range tests {
+		if test.decryptKey == "" {
+			test.decryptKey = test.encryptKey
+		}
+
+		encrypter, err := NewAlgorithm(test.name, test.encryptKey, test.opts)
+		if err != nil {
+			t.Errorf("%s: failed to create encrypter: %v", test.name, err)
+			continue
+		}
+
+		cipherText, err := encrypter.Encrypt("THIS IS A SECRET KEY")
+		if err != nil {
+			t.Errorf("%s: failed to encrypt: %v", test.name, err)
+			continue
+		}
+
+		decrypter, err := NewAlgorithm(test.name, test.decryptKey, AlgorithmOptions{})
+		if err != nil {
+			t.Errorf("%s: failed to create decrypter: %v", test.name, err)
+			continue
+		}
+
+		plainText, err := decrypter.Decrypt(cipherText)
+		if err != nil || plainText != "THIS IS A SECRET KEY" {
+			t.Errorf("%s: expected round trip, got %q, %v", test.name, plainText, err)
+		}
+	}
+}
+
+func TestRegistry_RegisterCustomAlgorithm(t *testing.T) {
+	Register("registry-test", "test algorithm", func(key string, _ AlgorithmOptions) (CryptoAlgorithm, error) {
+		return registryTestAlgorithm{key: key}, nil
+	})
+
+	algorithm, ok := Lookup("registry-test")
+	if !ok || algorithm.Description != "test algorithm" {
+		t.Fatalf("expected registered algorithm, got %v, %v", algorithm, ok)
+	}
+
+	found := false
+	for _, listed := range List() {
+		if listed.Name == "registry-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected registered algorithm in List")
+	}
+
+	crypto, err := NewAlgorithm("registry-test", "k", AlgorithmOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cipherText, _ := crypto.Encrypt("value")
+	if cipherText != "k:value" {
+		t.Errorf("expected custom algorithm to be used, got %q", cipherText)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate Register to panic")
+		}
+	}()
+	Register("registry-test", "duplicate", func(string, AlgorithmOptions) (CryptoAlgorithm, error) {
+		return nil, nil
+	})
+}
+
+func TestRegistry_ListIsSorted(t *testing.T) {
+	algorithms := List()
+	for i := 1; i < len(algorithms); i++ {
+		if algorithms[i-1].Name >= algorithms[i].Name {
+			t.Errorf("expected sorted list, got %s before %s", algorithms[i-1].Name, algorithms[i].Name)
+		}
+	}
+}
+
+func TestNewAlgorithm_Unsupported(t *testing.T) {
+	_, err := NewAlgorithm("does-not-exist", testKey, AlgorithmOptions{})
+	if err == nil || !strings.Contains(err.Error(), "unsupported algorithm") {
+		t.Errorf("expected unsupported algorithm error, got %v", err)
+	}
+}
+
+func TestNewAlgorithm_InvalidKeyID(t *testing.T) {
+	_, err := NewAlgorithm(AESGCMAlgorithm, testKey, AlgorithmOptions{KeyID: "prod,2026"})
+	if err == nil || !strings.Contains(err.Error(), "invalid key id") {
+		t.Errorf("expected invalid key id error, got %v", err)
+	}
+}
+
+func TestNewAlgorithm_PassphraseIterationCap(t *testing.T) {
+	_, err := NewAlgorithm(PassphraseAlgorithm, "passphrase", AlgorithmOptions{Iterations: DefaultMaxPBKDF2Iterations + 1})
+	if err == nil || !strings.Contains(err.Error(), "PBKDF2 iterations must be between") {
+		t.Errorf("expected iteration cap error, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package cryptography
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ed25519Signer signs data, e.g. a properties file, so tampering can be
+// detected by an Ed25519Verifier holding the public key
+type Ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+}
+
+// Ed25519Verifier checks signatures made by an Ed25519Signer
+type Ed25519Verifier struct {
+	publicKey ed25519.PublicKey
+}
+
+// GenerateEd25519KeyPair returns a new base64 encoded key pair, the private
+// key is the 32 byte seed
+func GenerateEd25519KeyPair() (string, string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(publicKey), base64.StdEncoding.EncodeToString(privateKey.Seed()), nil
+}
+
+// NewEd25519Signer accepts the 32 byte seed or the 64 byte private key
+func NewEd25519Signer(privateKey string) (*Ed25519Signer, error) {
+	key, err := decodeEd25519Key(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(key) {
+	case ed25519.SeedSize:
+		return &Ed25519Signer{privateKey: ed25519.NewKeyFromSeed(key)}, nil
+	case ed25519.PrivateKeySize:
+		return &Ed25519Signer{privateKey: ed25519.PrivateKey(key)}, nil
+	}
+
+	return nil, fmt.Errorf("ed25519 private keys must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(key))
+}
+
+func NewEd25519Verifier(publicKey string) (*Ed25519Verifier, error) {
+	key, err := decodeEd25519Key(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public keys must be exactly %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	return &Ed25519Verifier{publicKey: ed25519.PublicKey(key)}, nil
+}
+
+// Sign returns the base64 encoded signature of data
+func (s *Ed25519Signer) Sign(data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, data))
+}
+
+// Verify checks a base64 encoded signature of data, surrounding whitespace is
+// ignored
+func (v *Ed25519Verifier) Verify(data []byte, signature string) error {
+	decodedSignature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	if !ed25519.Verify(v.publicKey, data, decodedSignature) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
+
+// decodeEd25519Key accepts base64 keys as printed by GenerateEd25519KeyPair,
+// and the base64: and hex: prefixes understood by DecodeKey
+func decodeEd25519Key(encoded string) ([]byte, error) {
+	var key []byte
+	var err error
+
+	if strings.HasPrefix(encoded, "base64:") || strings.HasPrefix(encoded, "hex:") {
+		key, err = DecodeKey(encoded)
+	} else {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid ed25519 key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,92 @@
+package cryptography
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestEd25519_SignAndVerify(t *testing.T) {
+	publicKey, privateKey, err := GenerateEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	signer, err := NewEd25519Signer(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	verifier, err := NewEd25519Verifier(publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	data := []byte("FEATURE.BETA=true\n")
+	signature := signer.Sign(data)
+
+	err = verifier.Verify(data, signature+"\n")
+	if err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	err = verifier.Verify([]byte("FEATURE.BETA=false\n"), signature)
+	if err == nil || !strings.Contains(err.Error(), "signature does not match") {
+		t.Errorf("expected mismatch for tampered data, got %v", err)
+	}
+
+	otherPublicKey, _, _ := GenerateEd25519KeyPair()
+	otherVerifier, _ := NewEd25519Verifier(otherPublicKey)
+	err = otherVerifier.Verify(data, signature)
+	if err == nil {
+		t.Error("expected mismatch for another public key")
+	}
+}
+
+// RFC 8032 section 7.1, test 2
+func TestEd25519_RFC8032Vector(t *testing.T) {
+	seed := "hex:4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb"
+	publicKey := "hex:3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c"
+	expected := "92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da" +
+		"085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00"
+
+	signer, err := NewEd25519Signer(seed)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	signature := signer.Sign([]byte{0x72})
+	expectedSignature, _ := hex.DecodeString(expected)
+	if signature != base64.StdEncoding.EncodeToString(expectedSignature) {
+		t.Errorf("expected signature %s, got %s", expected, signature)
+	}
+
+	verifier, err := NewEd25519Verifier(publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	err = verifier.Verify([]byte{0x72}, signature)
+	if err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+}
+
+func TestEd25519_InvalidKeys(t *testing.T) {
+	_, err := NewEd25519Signer("base64:AAAA")
+	if err == nil || !strings.Contains(err.Error(), "ed25519 private keys must be") {
+		t.Errorf("expected key length error, got %v", err)
+	}
+
+	_, err = NewEd25519Verifier("hex:" + strings.Repeat("00", ed25519.PublicKeySize+1))
+	if err == nil || !strings.Contains(err.Error(), "ed25519 public keys must be") {
+		t.Errorf("expected key length error, got %v", err)
+	}
+
+	_, err = NewEd25519Verifier("not base64!")
+	if err == nil || !strings.Contains(err.Error(), "invalid ed25519 key") {
+		t.Errorf("expected encoding error, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Streams are encrypted in segments following the STREAM construction, so
+// large files can be encrypted and decrypted without holding them in memory:
+//
+//	header:   magic || salt(32)
+//	segments: AES-GCM(segment key, nonce = counter(11) || last(1), plaintext)
+//
+// Each stream gets its own key derived with HKDF-SHA256 from the key and the
+// random salt. Segments hold 64 KiB of plaintext, only the last one may be
+// shorter and it is sealed with the last flag set, so truncating, reordering
+// or appending segments is detected.
+const (
+	streamSegmentSize = 64 * 1024
+	streamSaltSize    = 32
+	streamInfo        = "configprovider stream v1"
+)
+
+var streamMagic = []byte("CPSTREAM1\n")
+
+// StreamMagicSize is the number of leading bytes IsStream needs to recognise
+// a stream, e.g. to peek at a file before reading it
+var StreamMagicSize = len(streamMagic)
+
+// IsStream reports whether data starts with the header written by
+// EncryptWriter
+func IsStream(data []byte) bool {
+	return bytes.HasPrefix(data, streamMagic)
+}
+
+// EncryptWriter returns a writer that encrypts everything written to it as a
+// stream to w. Close must be called to write the last segment, it doesn't
+// close w.
+func (c *AESGCMCrypto) EncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	return newStreamWriter(c.key, w)
+}
+
+// DecryptReader returns a reader of the plaintext of a stream written by
+// EncryptWriter. Reads fail if the stream was modified or truncated, so the
+// plaintext must not be trusted until the reader returns io.EOF.
+func (c *AESGCMCrypto) DecryptReader(r io.Reader) (io.Reader, error) {
+	return newStreamReader(c.key, r)
+}
+
+type streamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buffer  []byte
+	counter uint64
+	closed  bool
+	err     error
+}
+
+func newStreamWriter(key []byte, w io.Writer) (*streamWriter, error) {
+	salt := make([]byte, streamSaltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newStreamAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = w.Write(append(append([]byte{}, streamMagic...), salt...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamWriter{
+		w:      w,
+		aead:   aead,
+		buffer: make([]byte, 0, streamSegmentSize),
+	}, nil
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("write to closed stream")
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data arrives, the last
+		// segment has to be sealed differently by Close
+		if len(s.buffer) == streamSegmentSize {
+			s.err = s.flush(false)
+			if s.err != nil {
+				return written, s.err
+			}
+		}
+
+		n := copy(s.buffer[len(s.buffer):streamSegmentSize], p)
+		s.buffer = s.buffer[:len(s.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// Close seals the last segment
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.flush(true)
+}
+
+func (s *streamWriter) flush(last bool) error {
+	nonce, err := streamNonce(s.counter, last)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.w.Write(s.aead.Seal(nil, nonce, s.buffer, nil))
+	if err != nil {
+		return err
+	}
+
+	s.buffer = s.buffer[:0]
+	s.counter++
+	return nil
+}
+
+type streamReader struct {
+	r         *bufio.Reader
+	aead      cipher.AEAD
+	segment   []byte
+	plainText []byte
+	counter   uint64
+	done      bool
+	err       error
+}
+
+func newStreamReader(key []byte, r io.Reader) (*streamReader, error) {
+	header := make([]byte, len(streamMagic)+streamSaltSize)
+	_, err := io.ReadFull(r, header)
+	if err != nil || !IsStream(header) {
+		return nil, errors.New("not an encrypted stream")
+	}
+
+	aead, err := newStreamAEAD(key, header[len(streamMagic):])
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamReader{
+		r:       bufio.NewReaderSize(r, streamSegmentSize+aead.Overhead()+1),
+		aead:    aead,
+		segment: make([]byte, streamSegmentSize+aead.Overhead()),
+	}, nil
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plainText) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+
+		s.err = s.next()
+	}
+
+	n := copy(p, s.plainText)
+	s.plainText = s.plainText[n:]
+	return n, nil
+}
+
+// next decrypts the next segment, a segment is the last one if it is short or
+// nothing follows it
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.segment)
+	last := false
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		_, err = s.r.Peek(1)
+		if errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	nonce, err := streamNonce(s.counter, last)
+	if err != nil {
+		return err
+	}
+
+	plainText, err := s.aead.Open(s.segment[:0], nonce, s.segment[:n], nil)
+	if err != nil {
+		return fmt.Errorf("stream segment %d failed to decrypt, the stream was modified or truncated: %w", s.counter, err)
+	}
+
+	s.plainText = plainText
+	s.counter++
+	s.done = last
+	return nil
+}
+
+func newStreamAEAD(key []byte, salt []byte) (cipher.AEAD, error) {
+	streamKey, err := hkdf.Key(sha256.New, key, salt, streamInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive stream key: %w", err)
+	}
+
+	return newAESGCM(streamKey)
+}
+
+func streamNonce(counter uint64, last bool) ([]byte, error) {
+	if counter == math.MaxUint64 {
+		return nil, errors.New("stream is too long")
+	}
+
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+
+	return nonce, nil
+}
@@ -0,0 +1,117 @@
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+)
+
+func encryptTestStream(t *testing.T, crypto *AESGCMCrypto, plainText []byte) []byte {
+	t.Helper()
+
+	var output bytes.Buffer
+	writer, err := crypto.EncryptWriter(&output)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	// Write in uneven pieces so segments don't line up with writes
+	for len(plainText) > 0 {
+		n := min(len(plainText), 1000)
+		_, err = writer.Write(plainText[:n])
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		plainText = plainText[n:]
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	return output.Bytes()
+}
+
+func decryptTestStream(crypto *AESGCMCrypto, cipherText []byte) ([]byte, error) {
+	reader, err := crypto.DecryptReader(bytes.NewReader(cipherText))
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(reader)
+}
+
+func TestStream_RoundTrip(t *testing.T) {
+	crypto, _ := NewAESGCMCrypto(testKey)
+
+	sizes := []int{0, 1, streamSegmentSize - 1, streamSegmentSize, streamSegmentSize + 1, 3*streamSegmentSize + 17}
+	for _, size := range sizes {
+		plainText := make([]byte, size)
+		rand.Read(plainText)
+
+		cipherText := encryptTestStream(t, crypto, plainText)
+		if !IsStream(cipherText) {
+			t.Errorf("size %d: expected stream header", size)
+		}
+
+		decrypted, err := decryptTestStream(crypto, cipherText)
+		if err != nil {
+			t.Fatalf("size %d: decryption failed: %v", size, err)
+		}
+
+		if !bytes.Equal(decrypted, plainText) {
+			t.Errorf("size %d: decrypted data does not match", size)
+		}
+	}
+}
+
+func TestStream_DetectsTampering(t *testing.T) {
+	crypto, _ := NewAESGCMCrypto(testKey)
+
+	plainText := make([]byte, 2*streamSegmentSize+100)
+	rand.Read(plainText)
+	cipherText := encryptTestStream(t, crypto, plainText)
+
+	headerSize := len(streamMagic) + streamSaltSize
+	segmentSize := streamSegmentSize + 16
+	first := cipherText[headerSize : headerSize+segmentSize]
+	second := cipherText[headerSize+segmentSize : headerSize+2*segmentSize]
+
+	flipped := bytes.Clone(cipherText)
+	flipped[headerSize+10] ^= 1
+
+	tests := map[string][]byte{
+		"flipped bit":       flipped,
+		"truncated segment": cipherText[:len(cipherText)-1],
+		"dropped last":      cipherText[:headerSize+2*segmentSize],
+		"reordered":         bytes.Join([][]byte{cipherText[:headerSize], second, first, cipherText[headerSize+2*segmentSize:]}, nil),
+		"appended":          append(bytes.Clone(cipherText), 0),
+	}
+
+	for name, data := range tests {
+		_, err := decryptTestStream(crypto, data)
+		if err == nil || !strings.Contains(err.Error(), "modified or truncated") {
+			t.Errorf("%s: expected tampering error, got %v", name, err)
+		}
+	}
+}
+
+func TestStream_WrongKey(t *testing.T) {
+	crypto, _ := NewAESGCMCrypto(testKey)
+	otherCrypto, _ := NewAESGCMCrypto("abcdefghijklmnopqrstuvwxyz123456")
+
+	cipherText := encryptTestStream(t, crypto, []byte("secret file"))
+
+	_, err := decryptTestStream(otherCrypto, cipherText)
+	if err == nil {
+		t.Error("expected error decrypting with another key")
+	}
+
+	_, err = decryptTestStream(crypto, []byte("plain file"))
+	if err == nil || !strings.Contains(err.Error(), "not an encrypted stream") {
+		t.Errorf("expected header error, got %v", err)
+	}
+}
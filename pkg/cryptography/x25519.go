@@ -0,0 +1,240 @@
+package cryptography
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	X25519Algorithm = "x25519"
+	x25519Version   = "v1"
+	x25519KeySize   = 32
+	x25519Info      = "configprovider x25519 v1"
+)
+
+// X25519Crypto encrypts to a public key so anyone holding it can add secrets,
+// while only the holder of the private key can read them. Every value uses an
+// ephemeral X25519 key pair, the shared secret is expanded with HKDF-SHA256
+// into an AES-256-GCM key. The payload is ephemeralPublicKey||nonce||ciphertext.
+type X25519Crypto struct {
+	publicKey  *ecdh.PublicKey
+	privateKey *ecdh.PrivateKey
+}
+
+// GenerateX25519KeyPair returns a new base64 encoded key pair
+func GenerateX25519KeyPair() (string, string, error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key pair: %w", err)
+	}
+
+	publicKey := base64.StdEncoding.EncodeToString(privateKey.PublicKey().Bytes())
+	return publicKey, base64.StdEncoding.EncodeToString(privateKey.Bytes()), nil
+}
+
+// IsPublicKeyAlgorithm reports whether anyone holding a public key can
+// encrypt values of the named algorithm, so a valid ciphertext doesn't prove
+// who wrote it
+func IsPublicKeyAlgorithm(name string) bool {
+	return name == X25519Algorithm || name == MultiRecipientAlgorithm
+}
+
+// NewX25519Encrypter can only encrypt, Decrypt returns an error
+func NewX25519Encrypter(publicKey string) (*X25519Crypto, error) {
+	parsedKey, err := parseX25519PublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &X25519Crypto{publicKey: parsedKey}, nil
+}
+
+// NewX25519Crypto can encrypt and decrypt, the public key is derived from the
+// private key
+func NewX25519Crypto(privateKey string) (*X25519Crypto, error) {
+	key, err := decodeX25519Key(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKey, err := ecdh.X25519().NewPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 private key: %w", err)
+	}
+
+	return &X25519Crypto{publicKey: parsedKey.PublicKey(), privateKey: parsedKey}, nil
+}
+
+func (c *X25519Crypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *X25519Crypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *X25519Crypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *X25519Crypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *X25519Crypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	ephemeralPublicKey, key, err := x25519Wrap(c.publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := sealAESGCM(key, []byte(plainText), additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := Envelope{
+		Algorithm: X25519Algorithm,
+		Version:   x25519Version,
+		Params:    map[string]string{},
+		Payload:   base64.StdEncoding.EncodeToString(append(ephemeralPublicKey, sealed...)),
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *X25519Crypto) decrypt(cipherText string, key []byte) (string, error) {
+	if c.privateKey == nil {
+		return "", errors.New("x25519 decryption requires the private key")
+	}
+
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if envelope.Algorithm != X25519Algorithm {
+		return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, X25519Algorithm)
+	}
+	if envelope.Version != x25519Version {
+		return "", fmt.Errorf("unsupported %s version %s", X25519Algorithm, envelope.Version)
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+	if len(cipherData) < x25519KeySize {
+		return "", errors.New("ciphertext too short")
+	}
+
+	aesKey, err := x25519Unwrap(c.privateKey, cipherData[:x25519KeySize])
+	if err != nil {
+		return "", err
+	}
+
+	plainText, err := openAESGCM(aesKey, cipherData[x25519KeySize:], additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// x25519Wrap derives a fresh AES key for recipient, returning the ephemeral
+// public key the recipient needs to derive it again
+func x25519Wrap(recipient *ecdh.PublicKey) ([]byte, []byte, error) {
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	sharedSecret, err := ephemeralKey.ECDH(recipient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	ephemeralPublicKey := ephemeralKey.PublicKey().Bytes()
+	key, err := deriveX25519Key(sharedSecret, ephemeralPublicKey, recipient.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ephemeralPublicKey, key, nil
+}
+
+func x25519Unwrap(privateKey *ecdh.PrivateKey, ephemeralPublicKey []byte) ([]byte, error) {
+	ephemeralKey, err := ecdh.X25519().NewPublicKey(ephemeralPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral public key: %w", err)
+	}
+
+	sharedSecret, err := privateKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	return deriveX25519Key(sharedSecret, ephemeralPublicKey, privateKey.PublicKey().Bytes())
+}
+
+// deriveX25519Key binds both public keys into the salt so the key is specific
+// to this exchange
+func deriveX25519Key(sharedSecret []byte, ephemeralPublicKey []byte, recipientPublicKey []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeralPublicKey...), recipientPublicKey...)
+
+	key, err := hkdf.Key(sha256.New, sharedSecret, salt, x25519Info, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	return key, nil
+}
+
+func parseX25519PublicKey(publicKey string) (*ecdh.PublicKey, error) {
+	key, err := decodeX25519Key(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedKey, err := ecdh.X25519().NewPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 public key: %w", err)
+	}
+
+	return parsedKey, nil
+}
+
+// decodeX25519Key accepts base64 keys as printed by GenerateX25519KeyPair, and
+// the base64: and hex: prefixes understood by DecodeKey
+func decodeX25519Key(encoded string) ([]byte, error) {
+	var key []byte
+	var err error
+
+	if strings.HasPrefix(encoded, "base64:") || strings.HasPrefix(encoded, "hex:") {
+		key, err = DecodeKey(encoded)
+	} else {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 key: %w", err)
+	}
+
+	if len(key) != x25519KeySize {
+		return nil, fmt.Errorf("x25519 keys must be exactly %d bytes, got %d", x25519KeySize, len(key))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,102 @@
+package cryptography
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestX25519_EncryptWithPublicKeyOnly(t *testing.T) {
+	publicKey, privateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypter, err := NewX25519Encrypter(publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := encrypter.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	if !strings.HasPrefix(encrypted, "ENC[x25519,v1,") {
+		t.Errorf("expected an x25519 envelope, got %s", encrypted)
+	}
+
+	_, err = encrypter.Decrypt(encrypted)
+	if err == nil || !strings.Contains(err.Error(), "requires the private key") {
+		t.Errorf("expected public key only decryption to fail, got: %v", err)
+	}
+
+	decrypter, err := NewX25519Crypto(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	decrypted, err := decrypter.Decrypt(encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+}
+
+func TestX25519_WrongPrivateKey(t *testing.T) {
+	publicKey, _, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	_, otherPrivateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypter, _ := NewX25519Encrypter(publicKey)
+	encrypted, err := encrypter.Encrypt("super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	decrypter, _ := NewX25519Crypto(otherPrivateKey)
+	_, err = decrypter.Decrypt(encrypted)
+	if err == nil || !strings.Contains(err.Error(), "decryption failed") {
+		t.Errorf("expected decryption with another key to fail, got: %v", err)
+	}
+}
+
+func TestX25519_BoundToKey(t *testing.T) {
+	_, privateKey, err := GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	crypto, _ := NewX25519Crypto(privateKey)
+	encrypted, err := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	decrypted, err := crypto.DecryptFor("DB_PASSWORD", encrypted)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+
+	_, err = crypto.DecryptFor("ADMIN_TOKEN", encrypted)
+	if err == nil {
+		t.Errorf("expected value moved to another key to fail")
+	}
+}
+
+func TestX25519_InvalidKeys(t *testing.T) {
+	for _, key := range []string{"not base64!", "dGVzdA==", "hex:0001"} {
+		_, err := NewX25519Encrypter(key)
+		if err == nil {
+			t.Errorf("%s: expected invalid public key error", key)
+		}
+
+		_, err = NewX25519Crypto(key)
+		if err == nil {
+			t.Errorf("%s: expected invalid private key error", key)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cryptography
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	XChaCha20Poly1305Algorithm = "xchacha20poly1305"
+	xChaCha20Poly1305Version   = "v1"
+)
+
+// XChaCha20Poly1305Crypto uses 24 byte random nonces, which are safe to
+// generate randomly for any realistic number of values, and is fast without
+// AES hardware support. The payload is nonce||ciphertext. Like AESGCMCrypto
+// the AEAD is created once and shared by every call.
+type XChaCha20Poly1305Crypto struct {
+	aead cipher.AEAD
+}
+
+func NewXChaCha20Poly1305Crypto(key string) (*XChaCha20Poly1305Crypto, error) {
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, errors.New("XChaCha20Poly1305Crypto: key must be exactly 32 bytes")
+	}
+
+	aead, err := chacha20poly1305.NewX([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create xchacha20poly1305: %w", err)
+	}
+
+	return &XChaCha20Poly1305Crypto{aead: aead}, nil
+}
+
+func (c *XChaCha20Poly1305Crypto) Encrypt(plainText string) (string, error) {
+	return c.encrypt(plainText, nil)
+}
+
+func (c *XChaCha20Poly1305Crypto) EncryptFor(key string, plainText string) (string, error) {
+	return c.encrypt(plainText, []byte(key))
+}
+
+func (c *XChaCha20Poly1305Crypto) Decrypt(cipherText string) (string, error) {
+	return c.decrypt(cipherText, nil)
+}
+
+func (c *XChaCha20Poly1305Crypto) DecryptFor(key string, cipherText string) (string, error) {
+	return c.decrypt(cipherText, []byte(key))
+}
+
+func (c *XChaCha20Poly1305Crypto) encrypt(plainText string, additionalData []byte) (string, error) {
+	final, err := sealAEAD(c.aead, []byte(plainText), additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := Envelope{
+		Algorithm: XChaCha20Poly1305Algorithm,
+		Version:   xChaCha20Poly1305Version,
+		Params:    map[string]string{},
+		Payload:   base64.StdEncoding.EncodeToString(final),
+	}
+	if additionalData != nil {
+		envelope.Params["aad"] = "key"
+	}
+
+	return envelope.encode()
+}
+
+func (c *XChaCha20Poly1305Crypto) decrypt(cipherText string, key []byte) (string, error) {
+	envelope, err := ParseEnvelope(cipherText)
+	if err != nil {
+		return "", err
+	}
+
+	if envelope.Algorithm != XChaCha20Poly1305Algorithm {
+		return "", fmt.Errorf("unsupported algorithm %s, expected %s", envelope.Algorithm, XChaCha20Poly1305Algorithm)
+	}
+	if envelope.Version != xChaCha20Poly1305Version {
+		return "", fmt.Errorf("unsupported %s version %s", XChaCha20Poly1305Algorithm, envelope.Version)
+	}
+
+	additionalData, err := boundAdditionalData(envelope, key)
+	if err != nil {
+		return "", err
+	}
+
+	cipherData, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	plainText, err := openAEAD(c.aead, cipherData, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
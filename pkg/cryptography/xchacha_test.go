@@ -0,0 +1,119 @@
+package cryptography
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestXChaCha20Poly1305_EncryptionAndDecryption(t *testing.T) {
+	crypto, err := NewXChaCha20Poly1305Crypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encryption1, err1 := crypto.Encrypt("super-secret-value")
+	encryption2, err2 := crypto.Encrypt("super-secret-value")
+	if err1 != nil || err2 != nil {
+		t.Fatalf("encryption failed: %v, %v", err1, err2)
+	}
+
+	if !strings.HasPrefix(encryption1, "ENC[xchacha20poly1305,v1,") || encryption1 == encryption2 {
+		t.Errorf("expected unique xchacha20poly1305 envelopes, got %s and %s", encryption1, encryption2)
+	}
+
+	decrypted, err := crypto.Decrypt(encryption1)
+	if err != nil || decrypted != "super-secret-value" {
+		t.Errorf("expected round trip, got %q, %v", decrypted, err)
+	}
+}
+
+// The payload must be plain nonce||ciphertext so other XChaCha20-Poly1305
+// implementations can read and produce it
+func TestXChaCha20Poly1305_CrossCompatibility(t *testing.T) {
+	crypto, err := NewXChaCha20Poly1305Crypto(testKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	aead, err := chacha20poly1305.NewX([]byte(testKey))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	envelope, _ := ParseEnvelope(encrypted)
+	cipherData, _ := base64.StdEncoding.DecodeString(envelope.Payload)
+
+	plainText, err := aead.Open(nil, cipherData[:aead.NonceSize()], cipherData[aead.NonceSize():], []byte("DB_PASSWORD"))
+	if err != nil || string(plainText) != "super-secret-value" {
+		t.Errorf("expected reference implementation to decrypt, got %q, %v", plainText, err)
+	}
+
+	nonce := []byte("0123456789abcdef01234567")
+	sealed := aead.Seal(append([]byte{}, nonce...), nonce, []byte("from-reference"), nil)
+	external := Envelope{
+		Algorithm: XChaCha20Poly1305Algorithm,
+		Version:   "v1",
+		Payload:   base64.StdEncoding.EncodeToString(sealed),
+	}
+
+	decrypted, err := crypto.Decrypt(external.String())
+	if err != nil || decrypted != "from-reference" {
+		t.Errorf("expected value from reference implementation to decrypt, got %q, %v", decrypted, err)
+	}
+}
+
+func TestXChaCha20Poly1305_MixedWithAESGCM(t *testing.T) {
+	xchacha, _ := NewXChaCha20Poly1305Crypto(testKey)
+	aesGCM, _ := NewAESGCMCrypto(testKey)
+
+	dispatcher := NewDispatcher().
+		Add(XChaCha20Poly1305Algorithm, xchacha).
+		Add(AESGCMAlgorithm, aesGCM)
+
+	for _, algorithm := range []CryptoAlgorithm{xchacha, aesGCM} {
+		encrypted, err := algorithm.Encrypt("super-secret-value")
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+
+		decrypted, err := dispatcher.Decrypt(encrypted)
+		if err != nil || decrypted != "super-secret-value" {
+			t.Errorf("expected %s to decrypt, got %q, %v", encrypted, decrypted, err)
+		}
+	}
+
+	aesGCMValue, _ := aesGCM.Encrypt("super-secret-value")
+	_, err := xchacha.Decrypt(aesGCMValue)
+	if err == nil || !strings.Contains(err.Error(), "unsupported algorithm aesgcm") {
+		t.Errorf("expected aesgcm value to be rejected, got: %v", err)
+	}
+}
+
+func TestXChaCha20Poly1305_BoundToKey(t *testing.T) {
+	crypto, _ := NewXChaCha20Poly1305Crypto(testKey)
+
+	encrypted, err := crypto.EncryptFor("DB_PASSWORD", "super-secret-value")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	_, err = crypto.DecryptFor("ADMIN_TOKEN", encrypted)
+	if err == nil || !strings.Contains(err.Error(), "decryption failed") {
+		t.Errorf("expected value moved to another key to fail, got: %v", err)
+	}
+}
+
+func TestXChaCha20Poly1305_InvalidKeyLength(t *testing.T) {
+	_, err := NewXChaCha20Poly1305Crypto("invalid-key")
+	if err == nil {
+		t.Errorf("expected error for invalid key length")
+	}
+}
@@ -2,6 +2,9 @@ package provider
 
 import (
 	"fmt"
+	"io"
+	"reflect"
+	"sync"
 )
 
 type Encrypter interface {
@@ -17,15 +20,111 @@ type CryptoAlgorithm interface {
 	Encrypter
 }
 
+// KeyAwareDecrypter is preferred over Decrypter when implemented, it receives
+// the config key so values bound to their key can be authenticated
+type KeyAwareDecrypter interface {
+	DecryptFor(key string, cipherText string) (string, error)
+}
+
+// KeyAwareEncrypter binds the encrypted value to the config key it is stored
+// under
+type KeyAwareEncrypter interface {
+	EncryptFor(key string, plainText string) (string, error)
+}
+
+// StreamDecrypter decrypts files encrypted in segments, e.g. by lockbox
+// encrypt --in, it is used for fromfile and @file: references holding a stream
+type StreamDecrypter interface {
+	DecryptReader(r io.Reader) (io.Reader, error)
+}
+
+// selectDecrypter returns the decrypter named by the encrypted=<name> tag
+// option, or decrypter when the field uses the default one
+func selectDecrypter(tagOpts tagOptions, decrypter Decrypter, opts loadOptions) (Decrypter, error) {
+	if tagOpts.Decrypter == "" {
+		return decrypter, nil
+	}
+
+	namedDecrypter, ok := opts.namedDecrypters[tagOpts.Decrypter]
+	if !ok {
+		return nil, fmt.Errorf("no decrypter named %s is provided for %s", tagOpts.Decrypter, tagOpts.Key)
+	}
+
+	return namedDecrypter, nil
+}
+
 func decryptValue(key string, value string, decrypter Decrypter) (string, error) {
 	if decrypter == nil {
 		return "", fmt.Errorf("no decrypter is provided")
 	}
 
-	plainText, err := decrypter.Decrypt(value)
+	var plainText string
+	var err error
+
+	keyAwareDecrypter, ok := decrypter.(KeyAwareDecrypter)
+	if ok {
+		plainText, err = keyAwareDecrypter.DecryptFor(key, value)
+	} else {
+		plainText, err = decrypter.Decrypt(value)
+	}
 	if err != nil {
 		return "", fmt.Errorf("decryption failed for %s: %w", key, err)
 	}
 
 	return plainText, nil
 }
+
+// decryptStreamReader returns a reader of the plaintext of the stream in r
+func decryptStreamReader(key string, r io.Reader, decrypter Decrypter) (io.Reader, error) {
+	if decrypter == nil {
+		return nil, fmt.Errorf("no decrypter is provided for %s", key)
+	}
+
+	streamDecrypter, ok := decrypter.(StreamDecrypter)
+	if !ok {
+		return nil, fmt.Errorf("the decrypter for %s can't decrypt encrypted streams", key)
+	}
+
+	reader, err := streamDecrypter.DecryptReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed for %s: %w", key, err)
+	}
+
+	return reader, nil
+}
+
+// pendingDecryption is an encrypted field waiting for decryptParallel
+type pendingDecryption struct {
+	field      reflect.Value
+	key        string
+	cipherText string
+	decrypter  Decrypter
+
+	plainText string
+	err       error
+}
+
+// decryptParallel decrypts every pending field on up to workers goroutines.
+// Decrypters must be safe for concurrent use, the ones in the cryptography
+// package are.
+func decryptParallel(pending []*pendingDecryption, workers int) {
+	jobs := make(chan *pendingDecryption)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for decryption := range jobs {
+				decryption.plainText, decryption.err = decryptValue(decryption.key, decryption.cipherText, decryption.decrypter)
+			}
+		}()
+	}
+
+	for _, decryption := range pending {
+		jobs <- decryption
+	}
+	close(jobs)
+
+	wg.Wait()
+}
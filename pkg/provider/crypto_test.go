@@ -2,7 +2,12 @@ package provider
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
 )
 
 // Mocks
@@ -44,3 +49,113 @@ func TestDecryptValue_Success(t *testing.T) {
 		t.Errorf("expected 'decrypted', got: %q", plain)
 	}
 }
+
+type mockCryptoTestKeyAwareDecrypter struct {
+	mockCryptoTestDecrypter
+	Key string
+}
+
+func (m *mockCryptoTestKeyAwareDecrypter) DecryptFor(key string, _ string) (string, error) {
+	m.Key = key
+	return m.Value, m.Err
+}
+
+func TestDecryptValue_PrefersKeyAwareDecrypter(t *testing.T) {
+	mock := &mockCryptoTestKeyAwareDecrypter{mockCryptoTestDecrypter: mockCryptoTestDecrypter{Value: "decrypted"}}
+
+	plain, err := decryptValue("SECRET", "key", mock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if plain != "decrypted" || mock.Key != "SECRET" {
+		t.Errorf("expected DecryptFor to be called with SECRET, got: %q, %q", plain, mock.Key)
+	}
+}
+
+// newCryptoTestConfig builds a struct with count encrypted string fields
+// KEY_0..KEY_n and a source holding their encrypted values
+func newCryptoTestConfig(tb testing.TB, count int, crypto *cryptography.AESGCMCrypto) (reflect.Type, mockParseTestSource) {
+	tb.Helper()
+
+	fields := make([]reflect.StructField, count)
+	source := mockParseTestSource{}
+
+	for i := range count {
+		key := fmt.Sprintf("KEY_%d", i)
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("Field%d", i),
+			Type: reflect.TypeOf(""),
+			Tag:  reflect.StructTag(`config:"` + key + `,encrypted"`),
+		}
+
+		encrypted, err := crypto.EncryptFor(key, fmt.Sprintf("value-%d", i))
+		if err != nil {
+			tb.Fatalf("encryption failed: %v", err)
+		}
+		source[key] = encrypted
+	}
+
+	return reflect.StructOf(fields), source
+}
+
+func TestAssignFields_ParallelDecryption(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	configType, source := newCryptoTestConfig(t, 50, crypto)
+	config := reflect.New(configType).Elem()
+
+	err = assignFields(config, source, crypto, loadOptions{decryptWorkers: 8})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := range 50 {
+		expected := fmt.Sprintf("value-%d", i)
+		if got := config.Field(i).String(); got != expected {
+			t.Errorf("field %d: expected %q, got %q", i, expected, got)
+		}
+	}
+}
+
+func TestAssignFields_ParallelDecryptionError(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	configType, source := newCryptoTestConfig(t, 10, crypto)
+	source["KEY_3"] = source["KEY_4"]
+	source["KEY_7"] = source["KEY_8"]
+
+	err = assignFields(reflect.New(configType).Elem(), source, crypto, loadOptions{decryptWorkers: 4})
+	if err == nil || !strings.Contains(err.Error(), "decryption failed for KEY_3") {
+		t.Errorf("expected the first failing field in field order, got %v", err)
+	}
+}
+
+func benchmarkAssignFields(b *testing.B, workers int) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		b.Fatalf("error: %v", err)
+	}
+
+	configType, source := newCryptoTestConfig(b, 200, crypto)
+
+	for b.Loop() {
+		err := assignFields(reflect.New(configType).Elem(), source, crypto, loadOptions{decryptWorkers: workers})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAssignFields_200EncryptedFields(b *testing.B) {
+	benchmarkAssignFields(b, 0)
+}
+
+func BenchmarkAssignFields_200EncryptedFieldsParallel(b *testing.B) {
+	benchmarkAssignFields(b, 8)
+}
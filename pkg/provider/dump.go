@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type DumpFormat string
+
+const (
+	DumpProperties DumpFormat = "properties"
+	DumpJSON       DumpFormat = "json"
+	DumpYAML       DumpFormat = "yaml"
+)
+
+// dumpEntry is a single config field, rendered for every format
+type dumpEntry struct {
+	key        string
+	properties string
+	json       []byte
+}
+
+// Dump serialises a loaded config struct keyed by its config tag names, in
+// field order. Fields tagged encrypted and Secret values are rendered as
+// "****", so the output is safe to expose on a debug endpoint or attach to a
+// bug report.
+//
+// YAML values are written in flow style, i.e. as JSON. Untagged fields
+// decrypted by WithAutoDecryption are only masked by the provider's Dump.
+func Dump(cfg any, format DumpFormat) ([]byte, error) {
+	return dump(cfg, format, nil)
+}
+
+// dump masks the keys in masked on top of the encrypted and Secret fields
+func dump(cfg any, format DumpFormat, masked map[string]bool) ([]byte, error) {
+	reflectValue := reflect.ValueOf(cfg)
+	if reflectValue.Kind() == reflect.Ptr {
+		reflectValue = reflectValue.Elem()
+	}
+
+	if reflectValue.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dump expects a struct or a pointer to a struct and got %T", cfg)
+	}
+
+	// Secret methods are looked up through field addresses, so work on an
+	// addressable copy
+	target := reflect.New(reflectValue.Type()).Elem()
+	target.Set(reflectValue)
+
+	entries, err := dumpEntries(target, masked)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case DumpProperties:
+		return dumpProperties(entries), nil
+	case DumpJSON:
+		return dumpJSON(entries)
+	case DumpYAML:
+		return dumpYAML(entries), nil
+	}
+
+	return nil, fmt.Errorf("unsupported dump format: %s", format)
+}
+
+func dumpEntries(target reflect.Value, masked map[string]bool) ([]dumpEntry, error) {
+	targetType := target.Type()
+	entries := make([]dumpEntry, 0, target.NumField())
+
+	for i := range target.NumField() {
+		field := target.Field(i)
+		fieldType := targetType.Field(i)
+
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		tagOpts := parseTag(fieldType)
+		if tagOpts.Key == "" {
+			continue
+		}
+
+		entry := dumpEntry{key: tagOpts.Key}
+		if tagOpts.IsEncrypted || masked[tagOpts.Key] {
+			entry.properties = redacted
+			entry.json = []byte(`"` + redacted + `"`)
+			entries = append(entries, entry)
+			continue
+		}
+
+		jsonValue, err := json.Marshal(field.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("unable to dump %s: %w", tagOpts.Key, err)
+		}
+
+		entry.json = jsonValue
+		entry.properties = formatPropertiesValue(field, jsonValue)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// formatPropertiesValue renders value the way parseAndSetValue reads it back,
+// slices are comma separated and maps are JSON
+func formatPropertiesValue(value reflect.Value, jsonValue []byte) string {
+	if _, ok := asSecretSetter(value); ok {
+		return redacted
+	}
+
+	switch value.Kind() {
+	case reflect.Slice:
+		items := make([]string, value.Len())
+		for i := range value.Len() {
+			items[i] = formatPropertiesValue(value.Index(i), nil)
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		if value.IsNil() {
+			return ""
+		}
+		return string(jsonValue)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+func dumpProperties(entries []dumpEntry) []byte {
+	var buffer bytes.Buffer
+
+	for _, entry := range entries {
+		fmt.Fprintf(&buffer, "%s=%s\n", entry.key, entry.properties)
+	}
+
+	return buffer.Bytes()
+}
+
+func dumpJSON(entries []dumpEntry) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	buffer.WriteByte('{')
+	for i, entry := range entries {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		key, err := json.Marshal(entry.key)
+		if err != nil {
+			return nil, err
+		}
+
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(entry.json)
+	}
+	buffer.WriteByte('}')
+
+	var indented bytes.Buffer
+	err := json.Indent(&indented, buffer.Bytes(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+func dumpYAML(entries []dumpEntry) []byte {
+	var buffer bytes.Buffer
+
+	for _, entry := range entries {
+		fmt.Fprintf(&buffer, "%s: %s\n", yamlKey(entry.key), entry.json)
+	}
+
+	return buffer.Bytes()
+}
+
+// yamlKey quotes keys that aren't safe as plain YAML scalars
+func yamlKey(key string) string {
+	switch strings.ToLower(key) {
+	case "", "true", "false", "yes", "no", "on", "off", "null", "~":
+		quoted, _ := json.Marshal(key)
+		return string(quoted)
+	}
+
+	isPlain := key[0] != '-' && key[0] != '.' && (key[0] < '0' || key[0] > '9')
+	for _, r := range key {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && !isDigit && r != '_' && r != '.' && r != '-' {
+			isPlain = false
+			break
+		}
+	}
+
+	if isPlain {
+		return key
+	}
+
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
@@ -0,0 +1,127 @@
+package provider_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Reinami/configprovider/pkg/provider"
+)
+
+type mockDumpConfig struct {
+	AppName  string                  `config:"APP_NAME"`
+	Port     int                     `config:"PORT"`
+	Tags     []string                `config:"TAGS"`
+	Flags    map[string]bool         `config:"FLAGS"`
+	Password string                  `config:"DB.PASSWORD,encrypted"`
+	Token    provider.SecretString   `config:"TOKEN"`
+	Keys     []provider.SecretString `config:"KEYS"`
+	Untagged string
+}
+
+func loadDumpConfig(t *testing.T) mockDumpConfig {
+	t.Helper()
+
+	config := mockDumpConfig{}
+	err := provider.NewConfigProvider().
+		FromSource(mockSource{
+			"APP_NAME":    "TestService",
+			"PORT":        "8080",
+			"TAGS":        "a,b",
+			"FLAGS":       `{"beta":true}`,
+			"DB.PASSWORD": "ciphertext",
+			"TOKEN":       "token-value",
+			"KEYS":        "key-value,other-key-value",
+		}).
+		WithDecrypter(&mockDecrypter{Value: "password-value"}).
+		Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config.Untagged = "untagged-value"
+
+	return config
+}
+
+func assertNoSecrets(t *testing.T, output string) {
+	t.Helper()
+
+	for _, secret := range []string{"password-value", "token-value", "key-value", "ciphertext", "untagged-value"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("%q leaked in dump:\n%s", secret, output)
+		}
+	}
+}
+
+func TestDump_Properties(t *testing.T) {
+	config := loadDumpConfig(t)
+
+	output, err := provider.Dump(&config, provider.DumpProperties)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "APP_NAME=TestService\n" +
+		"PORT=8080\n" +
+		"TAGS=a,b\n" +
+		"FLAGS={\"beta\":true}\n" +
+		"DB.PASSWORD=****\n" +
+		"TOKEN=****\n" +
+		"KEYS=****,****\n"
+	if string(output) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+	assertNoSecrets(t, string(output))
+}
+
+func TestDump_JSON(t *testing.T) {
+	config := loadDumpConfig(t)
+
+	output, err := provider.Dump(config, provider.DumpJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertNoSecrets(t, string(output))
+
+	var decoded map[string]any
+	err = json.Unmarshal(output, &decoded)
+	if err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+
+	if decoded["PORT"] != float64(8080) || decoded["DB.PASSWORD"] != "****" || decoded["TOKEN"] != "****" {
+		t.Errorf("got incorrect values %v", decoded)
+	}
+
+	if strings.Index(string(output), "APP_NAME") > strings.Index(string(output), "KEYS") {
+		t.Errorf("expected keys in field order:\n%s", output)
+	}
+}
+
+func TestDump_YAML(t *testing.T) {
+	config := loadDumpConfig(t)
+
+	output, err := provider.Dump(&config, provider.DumpYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, line := range []string{`APP_NAME: "TestService"`, `PORT: 8080`, `TAGS: ["a","b"]`, `DB.PASSWORD: "****"`, `TOKEN: "****"`} {
+		if !strings.Contains(string(output), line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, output)
+		}
+	}
+	assertNoSecrets(t, string(output))
+}
+
+func TestDump_Errors(t *testing.T) {
+	_, err := provider.Dump("not a struct", provider.DumpJSON)
+	if err == nil || !strings.Contains(err.Error(), "dump expects a struct") {
+		t.Errorf("expected struct error but got %v", err)
+	}
+
+	_, err = provider.Dump(mockDumpConfig{}, provider.DumpFormat("toml"))
+	if err == nil || !strings.Contains(err.Error(), "unsupported dump format") {
+		t.Errorf("expected format error but got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
+	"github.com/Reinami/configprovider/pkg/sources"
+)
+
+// Fields tagged fromfile, or values starting with this prefix once
+// WithFileReferencePrefix is set, are read from the referenced file
+const fileReferencePrefix = "@file:"
+
+const defaultFileReferenceLimit int64 = 1 << 20 // 1 MiB
+
+func isFileReference(value string, tagOpts tagOptions, opts loadOptions) bool {
+	return tagOpts.IsFromFile || (opts.fileReferencePrefix && strings.HasPrefix(value, fileReferencePrefix))
+}
+
+// fileReferenceDir returns the directory relative references in the value of
+// key resolve against, the directory of the file defining key when the source
+// knows it and the working directory otherwise
+func fileReferenceDir(source Source, key string) string {
+	fileSource, ok := source.(sources.FileSource)
+	if !ok {
+		return ""
+	}
+
+	file, ok := fileSource.File(key)
+	if !ok {
+		return ""
+	}
+
+	return filepath.Dir(file)
+}
+
+// readFileReference reads the referenced file. Files holding an encrypted
+// stream are decrypted with decrypter while they are read, so the size limit
+// applies to the plaintext, and the second result is true.
+func readFileReference(key string, value string, dir string, decrypter Decrypter, opts loadOptions) (string, bool, error) {
+	path := strings.TrimPrefix(value, fileReferencePrefix)
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	limit := opts.fileSizeLimit
+	if limit <= 0 {
+		limit = defaultFileReferenceLimit
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to open file for %s: %w", key, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", false, fmt.Errorf("unable to stat file for %s: %w", key, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", false, fmt.Errorf("file %s for %s is not a regular file", path, key)
+	}
+
+	if opts.strictFilePermissions && info.Mode().Perm()&0o004 != 0 {
+		return "", false, fmt.Errorf("file %s for %s is world-readable", path, key)
+	}
+
+	bufferedFile := bufio.NewReader(file)
+	var reader io.Reader = bufferedFile
+
+	// A short or failed peek just means the file isn't a stream, ReadAll
+	// reports read errors below
+	header, _ := bufferedFile.Peek(cryptography.StreamMagicSize)
+	isStream := cryptography.IsStream(header)
+	if isStream {
+		reader, err = decryptStreamReader(key, bufferedFile, decrypter)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	// Read one byte past the limit so files that grow after the stat are caught
+	content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", false, fmt.Errorf("unable to read file for %s: %w", key, err)
+	}
+
+	if int64(len(content)) > limit {
+		return "", false, fmt.Errorf("file %s for %s exceeds the %d byte limit", path, key, limit)
+	}
+
+	return sources.TrimTrailingNewline(string(content)), isStream, nil
+}
@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
+	"github.com/Reinami/configprovider/pkg/sources"
+)
+
+type mockFileTestConfig struct {
+	Certificate string `config:"CERTIFICATE,fromfile"`
+	Password    string `config:"PASSWORD"`
+	Token       string `config:"TOKEN,fromfile,encrypted"`
+}
+
+func writeFileTestFile(t *testing.T, name string, content string, perm os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), perm)
+	if err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+
+	// Undo the umask so the permission checks see perm
+	err = os.Chmod(path, perm)
+	if err != nil {
+		t.Fatalf("failed to chmod %s: %v", name, err)
+	}
+
+	return path
+}
+
+// Tests
+
+func TestAssignFields_FileReferences(t *testing.T) {
+	certPath := writeFileTestFile(t, "cert.pem", "-----BEGIN CERTIFICATE-----\nabc\n-----END CERTIFICATE-----\n", 0600)
+	passwordPath := writeFileTestFile(t, "password", "hunter2\n", 0600)
+	tokenPath := writeFileTestFile(t, "token", "ciphertext\n", 0600)
+
+	config := mockFileTestConfig{}
+	err := assignFields(
+		reflect.ValueOf(&config).Elem(),
+		mockParseTestSource{
+			"CERTIFICATE": certPath,
+			"PASSWORD":    "@file:" + passwordPath,
+			"TOKEN":       tokenPath,
+		},
+		&mockParseTestDecrypter{Value: "decrypted-token"},
+		loadOptions{strictFilePermissions: true, fileReferencePrefix: true},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Certificate != "-----BEGIN CERTIFICATE-----\nabc\n-----END CERTIFICATE-----" ||
+		config.Password != "hunter2" ||
+		config.Token != "decrypted-token" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+}
+
+func TestReadFileReference_Errors(t *testing.T) {
+	worldReadable := writeFileTestFile(t, "world", "secret", 0644)
+	large := writeFileTestFile(t, "large", strings.Repeat("a", 11), 0600)
+
+	tests := []struct {
+		value    string
+		opts     loadOptions
+		expected string
+	}{
+		{worldReadable, loadOptions{strictFilePermissions: true}, "is world-readable"},
+		{large, loadOptions{fileSizeLimit: 10}, "exceeds the 10 byte limit"},
+		{filepath.Dir(large), loadOptions{}, "is not a regular file"},
+		{"@file:nonexistent/file", loadOptions{}, "unable to open file for KEY"},
+	}
+
+	for _, test := range tests {
+		_, _, err := readFileReference("KEY", test.value, "", nil, test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%q: expected error containing %q, got %v", test.value, test.expected, err)
+		}
+	}
+
+	value, _, err := readFileReference("KEY", worldReadable, "", nil, loadOptions{})
+	if err != nil || value != "secret" {
+		t.Errorf("expected world-readable file to be read outside strict mode, got %q, %v", value, err)
+	}
+}
+
+func TestAssignFields_EncryptedStreamFile(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	// Ends with a newline so the ciphertext can't be trimmed by accident
+	plainText := strings.Repeat("0123456789abcdef", 10000) + "\n"
+
+	var cipherText bytes.Buffer
+	writer, _ := crypto.EncryptWriter(&cipherText)
+	writer.Write([]byte(plainText))
+	writer.Close()
+
+	path := writeFileTestFile(t, "bundle.enc", cipherText.String(), 0600)
+
+	config := mockFileTestConfig{}
+	err = assignFields(
+		reflect.ValueOf(&config).Elem(),
+		mockParseTestSource{"TOKEN": path},
+		crypto,
+		loadOptions{strictEncryption: true},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Token != strings.TrimSuffix(plainText, "\n") {
+		t.Errorf("expected decrypted stream, got %d bytes", len(config.Token))
+	}
+
+	err = assignFields(
+		reflect.ValueOf(&config).Elem(),
+		mockParseTestSource{"TOKEN": path},
+		&mockParseTestDecrypter{Value: "decrypted"},
+		loadOptions{},
+	)
+	if err == nil || !strings.Contains(err.Error(), "can't decrypt encrypted streams") {
+		t.Errorf("expected stream decrypter error, got %v", err)
+	}
+}
+
+func TestReadFileReference_StreamLimitAppliesToPlainText(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	plainText := strings.Repeat("0123456789abcdef", 100)
+
+	var cipherText bytes.Buffer
+	writer, _ := crypto.EncryptWriter(&cipherText)
+	writer.Write([]byte(plainText))
+	writer.Close()
+
+	path := writeFileTestFile(t, "bundle.enc", cipherText.String(), 0600)
+
+	// The ciphertext is larger than the limit, the plaintext fits
+	value, isStream, err := readFileReference("KEY", path, "", crypto, loadOptions{fileSizeLimit: int64(len(plainText))})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !isStream || value != plainText {
+		t.Errorf("expected decrypted stream, got %d bytes (stream %v)", len(value), isStream)
+	}
+
+	_, _, err = readFileReference("KEY", path, "", crypto, loadOptions{fileSizeLimit: int64(len(plainText) - 1)})
+	if err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("expected limit error, got %v", err)
+	}
+}
+
+func TestAssignFields_FileReferencePrefixIsOptIn(t *testing.T) {
+	passwordPath := writeFileTestFile(t, "password", "hunter2\n", 0600)
+
+	config := mockFileTestConfig{}
+	err := assignFields(
+		reflect.ValueOf(&config).Elem(),
+		mockParseTestSource{"PASSWORD": "@file:" + passwordPath},
+		nil,
+		loadOptions{},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Password != "@file:"+passwordPath {
+		t.Errorf("expected the value to be left as it is, got %q", config.Password)
+	}
+}
+
+func TestAssignFields_RelativeFileReferences(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.properties":         "@include conf/common.properties\nPASSWORD=@file:secrets/password\n",
+		"conf/common.properties": "CERTIFICATE=certs/cert.pem\n",
+		"conf/certs/cert.pem":    "certificate\n",
+		"secrets/password":       "hunter2\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		err = os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	source, err := sources.NewPropertiesFileSource(filepath.Join(dir, "app.properties"))
+	if err != nil {
+		t.Fatalf("failed to read properties: %v", err)
+	}
+
+	config := mockFileTestConfig{}
+	err = assignFields(reflect.ValueOf(&config).Elem(), source, nil, loadOptions{fileReferencePrefix: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Certificate != "certificate" || config.Password != "hunter2" {
+		t.Errorf("expected paths relative to the defining file, got %v", config)
+	}
+}
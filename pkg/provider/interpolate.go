@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// interpolator expands expressions in config values against a Source and the
+// process environment. Supported expressions:
+//
+//	${OTHER.KEY}          value of another key in the source
+//	${ENV:HOME}           value of an environment variable
+//	${KEY:-fallback}      fallback when the key is missing or empty
+//	$${                   a literal "${"
+type interpolator struct {
+	source Source
+}
+
+func newInterpolator(source Source) *interpolator {
+	return &interpolator{source: source}
+}
+
+// expand resolves every expression in value. key is the config key the value
+// belongs to and is used for cycle detection.
+func (i *interpolator) expand(key string, value string) (string, error) {
+	return i.expandValue(value, []string{key})
+}
+
+func (i *interpolator) expandValue(value string, stack []string) (string, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	var builder strings.Builder
+
+	for pos := 0; pos < len(value); {
+		if strings.HasPrefix(value[pos:], "$${") {
+			builder.WriteString("${")
+			pos += 3
+			continue
+		}
+
+		if !strings.HasPrefix(value[pos:], "${") {
+			builder.WriteByte(value[pos])
+			pos++
+			continue
+		}
+
+		end, err := findClosingBrace(value, pos+2)
+		if err != nil {
+			return "", err
+		}
+
+		resolved, err := i.resolve(value[pos+2:end], stack)
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(resolved)
+		pos = end + 1
+	}
+
+	return builder.String(), nil
+}
+
+func (i *interpolator) resolve(expression string, stack []string) (string, error) {
+	name, fallback, hasFallback := strings.Cut(expression, ":-")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("empty interpolation expression in ${%s}", expression)
+	}
+
+	if envName, isEnv := strings.CutPrefix(name, "ENV:"); isEnv {
+		value, found := os.LookupEnv(envName)
+		if found && (value != "" || !hasFallback) {
+			return value, nil
+		}
+		if hasFallback {
+			return i.expandValue(fallback, stack)
+		}
+		return "", fmt.Errorf("environment variable %s referenced by %s is not set", envName, stack[len(stack)-1])
+	}
+
+	for _, seen := range stack {
+		if seen == name {
+			return "", fmt.Errorf("interpolation cycle detected: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+
+	value, found := i.source.Get(name)
+	if found && (value != "" || !hasFallback) {
+		return i.expandValue(value, append(stack, name))
+	}
+	if hasFallback {
+		return i.expandValue(fallback, stack)
+	}
+
+	return "", fmt.Errorf("key %s referenced by %s is missing", name, stack[len(stack)-1])
+}
+
+// findClosingBrace returns the index of the "}" closing the expression that
+// starts at start, skipping over nested expressions.
+func findClosingBrace(value string, start int) (int, error) {
+	depth := 1
+
+	for pos := start; pos < len(value); pos++ {
+		switch {
+		case strings.HasPrefix(value[pos:], "${"):
+			depth++
+			pos++
+		case value[pos] == '}':
+			depth--
+			if depth == 0 {
+				return pos, nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated interpolation expression in %q", value)
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests
+
+func TestInterpolator_ReferencesAndFallbacks(t *testing.T) {
+	t.Setenv("CONFIGPROVIDER_TEST_HOME", "/home/test")
+
+	source := mockParseTestSource{
+		"DB.HOST": "db.internal",
+		"DB.PORT": "5432",
+		"DB.URL":  "postgres://${DB.HOST}:${DB.PORT}/app",
+		"EMPTY":   "",
+	}
+
+	tests := map[string]string{
+		"${DB.URL}":                          "postgres://db.internal:5432/app",
+		"${ENV:CONFIGPROVIDER_TEST_HOME}/x":  "/home/test/x",
+		"${MISSING:-fallback}":               "fallback",
+		"${EMPTY:-fallback}":                 "fallback",
+		"${MISSING:-${DB.HOST}}":             "db.internal",
+		"${ENV:CONFIGPROVIDER_UNSET:-none}":  "none",
+		"literal $${DB.HOST} and ${DB.PORT}": "literal ${DB.HOST} and 5432",
+		"no expressions":                     "no expressions",
+	}
+
+	interpolator := newInterpolator(source)
+	for input, expected := range tests {
+		got, err := interpolator.expand("KEY", input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", input, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestInterpolator_Errors(t *testing.T) {
+	source := mockParseTestSource{
+		"A": "${B}",
+		"B": "${A}",
+	}
+
+	tests := map[string]string{
+		"${A}":                        "interpolation cycle detected: KEY -> A -> B -> A",
+		"${KEY}":                      "interpolation cycle detected: KEY -> KEY",
+		"${MISSING}":                  "key MISSING referenced by KEY is missing",
+		"${ENV:CONFIGPROVIDER_UNSET}": "environment variable CONFIGPROVIDER_UNSET referenced by KEY is not set",
+		"${UNTERMINATED":              "unterminated interpolation expression",
+		"${}":                         "empty interpolation expression",
+	}
+
+	interpolator := newInterpolator(source)
+	for input, expected := range tests {
+		_, err := interpolator.expand("KEY", input)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q: expected error containing %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestAssignFields_Interpolation(t *testing.T) {
+	type interpolationConfig struct {
+		URL      string `config:"URL"`
+		Fallback string `config:"FALLBACK,default=${HOST}:80"`
+	}
+
+	source := mockParseTestSource{
+		"HOST": "example.com",
+		"URL":  "https://${HOST}",
+	}
+
+	config := interpolationConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), source, nil, loadOptions{interpolate: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.URL != "https://example.com" || config.Fallback != "${HOST}:80" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	config = interpolationConfig{}
+	err = assignFields(reflect.ValueOf(&config).Elem(), source, nil, loadOptions{interpolateDefaults: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Fallback != "example.com:80" {
+		t.Errorf("expected interpolated default, got %q", config.Fallback)
+	}
+}
+
+func TestAssignFields_InterpolationIsOptIn(t *testing.T) {
+	type plainConfig struct {
+		Password string `config:"PASSWORD"`
+		Template string `config:"TEMPLATE"`
+	}
+
+	source := mockParseTestSource{
+		"PASSWORD": "pa${ss",
+		"TEMPLATE": "literal $${kept}",
+	}
+
+	config := plainConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), source, nil, loadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Password != "pa${ss" || config.Template != "literal $${kept}" {
+		t.Errorf("expected values to be left as they are, got %v", config)
+	}
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// LazySecret keeps an encrypted value encrypted until it is needed. Load
+// stores the ciphertext and the field's decrypter, Get decrypts it, so
+// decryption failures and plaintext only show up in the code paths that
+// use the secret.
+//
+// The plaintext isn't cached unless the provider is built with
+// WithLazySecretTTL. Fields of this type must be tagged encrypted.
+//
+//	type Config struct {
+//		BackupToken provider.LazySecret `config:"BACKUP_TOKEN,encrypted"`
+//	}
+type LazySecret struct {
+	state *lazySecretState
+}
+
+type lazySecretState struct {
+	mu         sync.Mutex
+	key        string
+	cipherText string
+	decrypter  Decrypter
+	ttl        time.Duration
+	now        func() time.Time
+
+	plainText string
+	cachedAt  time.Time
+	cached    bool
+}
+
+// Get decrypts the secret, or returns the cached plaintext when a TTL is set
+// and it hasn't expired
+func (s LazySecret) Get() (string, error) {
+	if s.state == nil {
+		return "", errors.New("lazy secret is not loaded")
+	}
+
+	return s.state.get()
+}
+
+// Forget drops the cached plaintext, the next Get decrypts again
+func (s LazySecret) Forget() {
+	if s.state == nil {
+		return
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	s.state.forget()
+}
+
+func (s LazySecret) String() string {
+	return redacted
+}
+
+func (s LazySecret) GoString() string {
+	return redacted
+}
+
+func (s LazySecret) Format(f fmt.State, _ rune) {
+	_, _ = io.WriteString(f, redacted)
+}
+
+func (s LazySecret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s LazySecret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (s *lazySecretState) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached && s.now().Sub(s.cachedAt) < s.ttl {
+		return s.plainText, nil
+	}
+	s.forget()
+
+	plainText, err := decryptValue(s.key, s.cipherText, s.decrypter)
+	if err != nil {
+		return "", err
+	}
+
+	if s.ttl > 0 {
+		s.plainText = plainText
+		s.cachedAt = s.now()
+		s.cached = true
+	}
+
+	return plainText, nil
+}
+
+func (s *lazySecretState) forget() {
+	s.plainText = ""
+	s.cached = false
+}
+
+var lazySecretType = reflect.TypeOf(LazySecret{})
+
+// setLazySecret stores the ciphertext in a LazySecret field, decrypting it is
+// left to Get
+func setLazySecret(field reflect.Value, key string, cipherText string, decrypter Decrypter, opts loadOptions) {
+	field.Set(reflect.ValueOf(LazySecret{state: &lazySecretState{
+		key:        key,
+		cipherText: cipherText,
+		decrypter:  decrypter,
+		ttl:        opts.lazySecretTTL,
+		now:        time.Now,
+	}}))
+}
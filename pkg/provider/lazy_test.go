@@ -0,0 +1,148 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockLazyTestDecrypter struct {
+	mu    sync.Mutex
+	Calls int
+	Err   error
+}
+
+func (m *mockLazyTestDecrypter) Decrypt(cipherText string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Calls++
+	return "plain:" + cipherText, m.Err
+}
+
+type mockLazyTestConfig struct {
+	Token LazySecret `config:"TOKEN,encrypted"`
+}
+
+func loadLazyTestConfig(t *testing.T, decrypter Decrypter, opts loadOptions) mockLazyTestConfig {
+	t.Helper()
+
+	config := mockLazyTestConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), mockParseTestSource{"TOKEN": "ciphertext"}, decrypter, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return config
+}
+
+func TestLazySecret_DecryptsOnGet(t *testing.T) {
+	decrypter := &mockLazyTestDecrypter{}
+	config := loadLazyTestConfig(t, decrypter, loadOptions{})
+
+	if decrypter.Calls != 0 {
+		t.Fatalf("expected no decryption during load, got %d calls", decrypter.Calls)
+	}
+
+	for range 2 {
+		value, err := config.Token.Get()
+		if err != nil || value != "plain:ciphertext" {
+			t.Errorf("expected decrypted value, got %q, %v", value, err)
+		}
+	}
+
+	if decrypter.Calls != 2 {
+		t.Errorf("expected every Get to decrypt without a TTL, got %d calls", decrypter.Calls)
+	}
+}
+
+func TestLazySecret_CachesWithTTL(t *testing.T) {
+	decrypter := &mockLazyTestDecrypter{}
+	config := loadLazyTestConfig(t, decrypter, loadOptions{lazySecretTTL: time.Minute})
+
+	now := time.Now()
+	config.Token.state.now = func() time.Time { return now }
+
+	_, _ = config.Token.Get()
+	_, _ = config.Token.Get()
+	if decrypter.Calls != 1 {
+		t.Errorf("expected cached value within the TTL, got %d calls", decrypter.Calls)
+	}
+
+	now = now.Add(time.Minute)
+	_, _ = config.Token.Get()
+	if decrypter.Calls != 2 {
+		t.Errorf("expected decryption after the TTL expired, got %d calls", decrypter.Calls)
+	}
+
+	config.Token.Forget()
+	_, _ = config.Token.Get()
+	if decrypter.Calls != 3 {
+		t.Errorf("expected decryption after Forget, got %d calls", decrypter.Calls)
+	}
+}
+
+func TestLazySecret_ErrorsOnGet(t *testing.T) {
+	decrypter := &mockLazyTestDecrypter{Err: errors.New("bad key")}
+	config := loadLazyTestConfig(t, decrypter, loadOptions{})
+
+	_, err := config.Token.Get()
+	if err == nil || !strings.Contains(err.Error(), "decryption failed for TOKEN") {
+		t.Errorf("expected decryption error from Get, got %v", err)
+	}
+
+	_, err = LazySecret{}.Get()
+	if err == nil {
+		t.Error("expected error for a LazySecret that wasn't loaded")
+	}
+}
+
+func TestLazySecret_LoadErrors(t *testing.T) {
+	config := mockLazyTestConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), mockParseTestSource{"TOKEN": "ciphertext"}, nil, loadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no decrypter is provided for TOKEN") {
+		t.Errorf("expected missing decrypter error, got %v", err)
+	}
+
+	untagged := struct {
+		Token LazySecret `config:"TOKEN"`
+	}{}
+	err = assignFields(reflect.ValueOf(&untagged).Elem(), mockParseTestSource{"TOKEN": "ciphertext"}, &mockLazyTestDecrypter{}, loadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "must be tagged encrypted") {
+		t.Errorf("expected untagged LazySecret error, got %v", err)
+	}
+}
+
+func TestLazySecret_Redaction(t *testing.T) {
+	config := loadLazyTestConfig(t, &mockLazyTestDecrypter{}, loadOptions{lazySecretTTL: time.Minute})
+	_, _ = config.Token.Get()
+
+	jsonOutput, _ := json.Marshal(config)
+	for _, output := range []string{fmt.Sprintf("%v %+v %#v", config, config, config), string(jsonOutput)} {
+		if strings.Contains(output, "ciphertext") || !strings.Contains(output, redacted) {
+			t.Errorf("expected redacted output, got %q", output)
+		}
+	}
+}
+
+func TestLazySecret_ConcurrentGet(t *testing.T) {
+	config := loadLazyTestConfig(t, &mockLazyTestDecrypter{}, loadOptions{lazySecretTTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := config.Token.Get()
+			if err != nil || value != "plain:ciphertext" {
+				t.Errorf("expected decrypted value, got %q, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
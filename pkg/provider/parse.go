@@ -7,10 +7,38 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
 )
 
-func assignFields(target reflect.Value, source Source, decrypter Decrypter) error {
+type loadOptions struct {
+	interpolate           bool  // If ${...} expressions in source values are resolved
+	interpolateDefaults   bool  // If default= tag values are interpolated as well
+	fileSizeLimit         int64 // Max size of a referenced file, 0 uses the default
+	strictFilePermissions bool  // If world-readable referenced files are refused
+	fileReferencePrefix   bool  // If values starting with @file: are read from the file
+
+	namedDecrypters  map[string]Decrypter // Decrypters selected with encrypted=<name>
+	lazySecretTTL    time.Duration        // How long LazySecret fields cache the plaintext
+	strictEncryption bool                 // If encrypted fields must hold an ENC[...] envelope
+	autoDecrypt      bool                 // If untagged ENC[...] values are decrypted with the default decrypter
+	autoDecrypted    map[string]bool      // Collects keys decrypted without the encrypted tag, may be nil
+	decryptWorkers   int                  // Decrypt fields on this many goroutines, 0 or 1 decrypts inline
+}
+
+// recordAutoDecrypted remembers fields decrypted without the encrypted tag,
+// so the provider's Dump masks them as well
+func (o loadOptions) recordAutoDecrypted(tagOpts tagOptions) {
+	if !tagOpts.IsEncrypted && o.autoDecrypted != nil {
+		o.autoDecrypted[tagOpts.Key] = true
+	}
+}
+
+func assignFields(target reflect.Value, source Source, decrypter Decrypter, opts loadOptions) error {
 	targetType := target.Type()
+	interpolator := newInterpolator(source)
+	var pending []*pendingDecryption
 
 	for i := range target.NumField() {
 		var finalValue string
@@ -28,9 +56,11 @@ func assignFields(target reflect.Value, source Source, decrypter Decrypter) erro
 		}
 
 		finalValue, found := source.Get(tagOpts.Key)
+		interpolate := found && opts.interpolate
 		if !found {
 			if tagOpts.Default != "" {
 				finalValue = tagOpts.Default
+				interpolate = opts.interpolateDefaults
 			} else if tagOpts.IsRequired {
 				return fmt.Errorf("required key %s is missing", tagOpts.Key)
 			} else {
@@ -38,20 +68,108 @@ func assignFields(target reflect.Value, source Source, decrypter Decrypter) erro
 			}
 		}
 
-		if tagOpts.IsEncrypted {
-			decryptedValue, err := decryptValue(tagOpts.Key, finalValue, decrypter)
+		if interpolate {
+			interpolatedValue, err := interpolator.expand(tagOpts.Key, finalValue)
+			if err != nil {
+				return err
+			}
+			finalValue = interpolatedValue
+		}
+
+		isStream := false
+		if isFileReference(finalValue, tagOpts, opts) {
+			dir := ""
+			if found {
+				dir = fileReferenceDir(source, tagOpts.Key)
+			}
+
+			// Files encrypted as a stream are decrypted while they are read,
+			// instead of going through the envelope decrypter afterwards
+			fieldDecrypter, err := selectDecrypter(tagOpts, decrypter, opts)
+			if err != nil {
+				return err
+			}
+
+			finalValue, isStream, err = readFileReference(tagOpts.Key, finalValue, dir, fieldDecrypter, opts)
+			if err != nil {
+				return err
+			}
+
+			if isStream {
+				if field.Type() == lazySecretType {
+					return fmt.Errorf("LazySecret field %s can't reference an encrypted stream", tagOpts.Key)
+				}
+
+				opts.recordAutoDecrypted(tagOpts)
+			}
+		}
+
+		if tagOpts.IsEncrypted && opts.strictEncryption && !isStream && !cryptography.IsEnvelope(finalValue) {
+			return fmt.Errorf("%s is tagged encrypted but its value is not an ENC[...] envelope", tagOpts.Key)
+		}
+
+		// With WithAutoDecryption values in envelope form are decrypted even
+		// without the encrypted tag, only the default decrypter is used
+		isAutoDecrypted := opts.autoDecrypt && decrypter != nil && cryptography.IsEnvelope(finalValue)
+		isEncrypted := !isStream && (tagOpts.IsEncrypted || isAutoDecrypted)
+
+		if isEncrypted {
+			opts.recordAutoDecrypted(tagOpts)
+
+			fieldDecrypter, err := selectDecrypter(tagOpts, decrypter, opts)
+			if err != nil {
+				return err
+			}
+
+			if field.Type() == lazySecretType {
+				if fieldDecrypter == nil {
+					return fmt.Errorf("no decrypter is provided for %s", tagOpts.Key)
+				}
+
+				setLazySecret(field, tagOpts.Key, finalValue, fieldDecrypter, opts)
+				continue
+			}
+
+			if opts.decryptWorkers > 1 {
+				pending = append(pending, &pendingDecryption{
+					field:      field,
+					key:        tagOpts.Key,
+					cipherText: finalValue,
+					decrypter:  fieldDecrypter,
+				})
+				continue
+			}
+
+			decryptedValue, err := decryptValue(tagOpts.Key, finalValue, fieldDecrypter)
 			if err != nil {
 				return err
 			}
 			finalValue = decryptedValue
 		}
 
+		if field.Type() == lazySecretType {
+			return fmt.Errorf("LazySecret field %s must be tagged encrypted", tagOpts.Key)
+		}
+
 		err := parseAndSetValue(field, finalValue)
 		if err != nil {
 			return err
 		}
 	}
 
+	decryptParallel(pending, opts.decryptWorkers)
+
+	for _, decryption := range pending {
+		if decryption.err != nil {
+			return decryption.err
+		}
+
+		err := parseAndSetValue(decryption.field, decryption.plainText)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -60,6 +178,10 @@ func parseAndSetValue(field reflect.Value, rawValue string) error {
 		return errors.New("field is not settable")
 	}
 
+	if setter, ok := asSecretSetter(field); ok {
+		return setter.setSecret(rawValue)
+	}
+
 	switch field.Kind() {
 
 	case reflect.String:
@@ -164,10 +286,13 @@ type tagOptions struct {
 	Default     string // The default value of the config
 	IsRequired  bool   // If the field is IsRequired
 	IsEncrypted bool   // If the field is encrypted
+	Decrypter   string // The named decrypter to use, empty for the default one
+	IsFromFile  bool   // If the value is a path to a file holding the value
 }
 
 // Example tag:
-// `config:"PORT,default=8000,required,encrypted"`
+// `config:"PORT,default=8000,required,encrypted,fromfile"`
+// `config:"DB_PASSWORD,encrypted=legacy"`
 func parseTag(field reflect.StructField) tagOptions {
 	rawTag := field.Tag.Get("config")
 	if rawTag == "" {
@@ -186,6 +311,11 @@ func parseTag(field reflect.StructField) tagOptions {
 			options.IsRequired = true
 		case trimmedPart == "encrypted":
 			options.IsEncrypted = true
+		case strings.HasPrefix(trimmedPart, "encrypted="):
+			options.IsEncrypted = true
+			options.Decrypter = strings.TrimSpace(strings.TrimPrefix(trimmedPart, "encrypted="))
+		case trimmedPart == "fromfile":
+			options.IsFromFile = true
 		case strings.HasPrefix(trimmedPart, "default="):
 			options.Default = strings.TrimPrefix(trimmedPart, "default=")
 		}
@@ -45,7 +45,7 @@ func TestAssignFields_MissingRequired(t *testing.T) {
 
 	value := reflect.ValueOf(&config).Elem()
 
-	err := assignFields(value, &mockParseTestSource{}, nil)
+	err := assignFields(value, &mockParseTestSource{}, nil, loadOptions{})
 	if err == nil || !strings.Contains(err.Error(), "required key REQUIRED_FIELD is missing") {
 		t.Errorf("expected error for missing field but got %v", err)
 	}
@@ -63,6 +63,7 @@ func TestAssignFields_DecryptionError(t *testing.T) {
 			"ENCRYPTED_FIELD": "encrypted",
 		},
 		&mockParseTestDecrypter{Err: errors.New("decryption error")},
+		loadOptions{},
 	)
 	if err == nil || !strings.Contains(err.Error(), "decryption failed for ENCRYPTED_FIELD") {
 		t.Errorf("expected error from decrypter but got %v", err)
@@ -74,7 +75,7 @@ func TestAssignFields_ParseError(t *testing.T) {
 
 	value := reflect.ValueOf(&config).Elem()
 
-	err := assignFields(value, &mockParseTestSource{"TEST_FIELD": "notabool"}, nil)
+	err := assignFields(value, &mockParseTestSource{"TEST_FIELD": "notabool"}, nil, loadOptions{})
 	if err == nil || !strings.Contains(err.Error(), "unable to parse") {
 		t.Errorf("expected error from parser but got %v", err)
 	}
@@ -97,6 +98,7 @@ func TestAssignFields_Success(t *testing.T) {
 			"FLOAT_FIELD":     "2.3",
 		},
 		&mockParseTestDecrypter{Value: "encrypted"},
+		loadOptions{},
 	)
 
 	if err != nil {
@@ -117,3 +119,92 @@ func TestAssignFields_Success(t *testing.T) {
 		t.Errorf("got incorrect fields on config %v", config)
 	}
 }
+
+func TestAssignFields_NamedDecrypter(t *testing.T) {
+	type namedDecrypterConfig struct {
+		Current string `config:"CURRENT,encrypted"`
+		Legacy  string `config:"LEGACY,encrypted=legacy"`
+	}
+
+	source := mockParseTestSource{"CURRENT": "current", "LEGACY": "legacy"}
+	opts := loadOptions{namedDecrypters: map[string]Decrypter{
+		"legacy": &mockParseTestDecrypter{Value: "from legacy"},
+	}}
+
+	config := namedDecrypterConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), source, &mockParseTestDecrypter{Value: "from default"}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Current != "from default" || config.Legacy != "from legacy" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	config = namedDecrypterConfig{}
+	err = assignFields(reflect.ValueOf(&config).Elem(), source, &mockParseTestDecrypter{Value: "from default"}, loadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no decrypter named legacy is provided for LEGACY") {
+		t.Errorf("expected missing named decrypter error but got %v", err)
+	}
+}
+
+func TestAssignFields_AutoDecryptsEnvelopes(t *testing.T) {
+	type autoDecryptConfig struct {
+		Untagged string `config:"UNTAGGED"`
+		Plain    string `config:"PLAIN"`
+	}
+
+	source := mockParseTestSource{"UNTAGGED": "ENC[aesgcm,v1,abc]", "PLAIN": "plain"}
+
+	config := autoDecryptConfig{}
+	decrypted := map[string]bool{}
+	opts := loadOptions{autoDecrypt: true, autoDecrypted: decrypted}
+
+	err := assignFields(reflect.ValueOf(&config).Elem(), source, &mockParseTestDecrypter{Value: "decrypted"}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Untagged != "decrypted" || config.Plain != "plain" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+	if !decrypted["UNTAGGED"] || decrypted["PLAIN"] {
+		t.Errorf("expected only UNTAGGED to be recorded, got %v", decrypted)
+	}
+
+	config = autoDecryptConfig{}
+	err = assignFields(reflect.ValueOf(&config).Elem(), source, nil, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Untagged != "ENC[aesgcm,v1,abc]" {
+		t.Errorf("expected envelope to be kept without a decrypter, got %q", config.Untagged)
+	}
+
+	config = autoDecryptConfig{}
+	err = assignFields(reflect.ValueOf(&config).Elem(), source, &mockParseTestDecrypter{Value: "decrypted"}, loadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Untagged != "ENC[aesgcm,v1,abc]" {
+		t.Errorf("expected envelope to be kept without WithAutoDecryption, got %q", config.Untagged)
+	}
+}
+
+func TestAssignFields_StrictEncryption(t *testing.T) {
+	type strictConfig struct {
+		Secret string `config:"SECRET,encrypted"`
+	}
+
+	decrypter := &mockParseTestDecrypter{Value: "decrypted"}
+	opts := loadOptions{strictEncryption: true}
+
+	config := strictConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), mockParseTestSource{"SECRET": "committed plaintext"}, decrypter, opts)
+	if err == nil || !strings.Contains(err.Error(), "SECRET is tagged encrypted but its value is not an ENC[...] envelope") {
+		t.Errorf("expected strict encryption error but got %v", err)
+	}
+
+	err = assignFields(reflect.ValueOf(&config).Elem(), mockParseTestSource{"SECRET": "ENC[aesgcm,v1,abc]"}, decrypter, opts)
+	if err != nil || config.Secret != "decrypted" {
+		t.Errorf("expected envelope to be decrypted, got %q, %v", config.Secret, err)
+	}
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Reinami/configprovider/pkg/sources"
+)
+
+// profileFilePath returns the overlay path for a profile, e.g.
+// app.properties + prod => app-prod.properties
+func profileFilePath(path string, profile string) string {
+	extension := filepath.Ext(path)
+	return strings.TrimSuffix(path, extension) + "-" + profile + extension
+}
+
+func splitProfiles(rawProfiles string) []string {
+	var profiles []string
+	for _, profile := range strings.Split(rawProfiles, ",") {
+		profile = strings.TrimSpace(profile)
+		if profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	return profiles
+}
+
+func (c *configProvider) activeProfiles(base Source) []string {
+	profiles := append([]string{}, c.profiles...)
+
+	if c.profilesEnv != "" {
+		profiles = append(profiles, splitProfiles(os.Getenv(c.profilesEnv))...)
+	}
+
+	if c.profilesKey != "" {
+		rawProfiles, _ := base.Get(c.profilesKey)
+		profiles = append(profiles, splitProfiles(rawProfiles)...)
+	}
+
+	return profiles
+}
+
+func (c *configProvider) propertiesFileLayer(path string) sourceLayer {
+	return sourceLayer{
+		name: path,
+		open: func() (Source, error) { return c.newPropertiesFileSource(path) },
+	}
+}
+
+// newPropertiesFileSource loads path and layers every existing profile file on
+// top of it. Missing profile files are skipped.
+func (c *configProvider) newPropertiesFileSource(path string) (*sources.LayeredSource, error) {
+	base, err := c.readPropertiesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	layered := sources.NewLayeredSource().Add(path, base)
+
+	for _, profile := range c.activeProfiles(base) {
+		overlayPath := profileFilePath(path, profile)
+
+		// Only the overlay itself may be missing, errors from files it
+		// includes must not drop the whole profile
+		_, err := os.Stat(overlayPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		overlay, err := c.readPropertiesFile(overlayPath)
+		if err != nil {
+			return nil, err
+		}
+
+		layered.Add(fmt.Sprintf("%s (profile %s)", overlayPath, profile), overlay)
+	}
+
+	return layered, nil
+}
+
+// readPropertiesFile reads a single properties file, verifying its signature
+// when WithSignatureVerification is set and decrypting every value when it was
+// opened with FromEncryptedPropertiesFile
+func (c *configProvider) readPropertiesFile(path string) (Source, error) {
+	var opts []sources.PropertiesOption
+	if c.verifier != nil {
+		opts = append(opts, sources.WithSignatureVerifier(c.verifier))
+	}
+
+	source, err := sources.NewPropertiesFileSource(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.encryptedFiles {
+		return source, nil
+	}
+
+	if c.decrypter == nil {
+		return nil, fmt.Errorf("no decrypter is provided for encrypted file %s", path)
+	}
+
+	encryptedSource, err := sources.NewEncryptedSource(source, c.decrypter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted file %s: %w", path, err)
+	}
+
+	// Fields don't need the encrypted tag here, the provider's Dump must
+	// still mask every value of the file
+	for _, key := range encryptedSource.Keys() {
+		c.autoDecrypted[key] = true
+	}
+
+	return encryptedSource, nil
+}
@@ -0,0 +1,259 @@
+package provider_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
+	"github.com/Reinami/configprovider/pkg/provider"
+	"github.com/Reinami/configprovider/pkg/sources"
+)
+
+type profilesTestConfig struct {
+	Host    string `config:"HOST"`
+	Port    int    `config:"PORT"`
+	Region  string `config:"REGION"`
+	Profile string `config:"PROFILES"`
+}
+
+func writeProfilesTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return filepath.Join(dir, "app.properties")
+}
+
+func TestConfigProvider_Profiles(t *testing.T) {
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties":      "HOST=localhost\nPORT=8080\nREGION=local\n",
+		"app-prod.properties": "HOST=prod.internal\nREGION=us\n",
+		"app-eu.properties":   "REGION=eu\n",
+	})
+
+	config := profilesTestConfig{}
+	p := provider.NewConfigProvider().
+		WithProfiles("prod", "eu", "missing").
+		FromPropertiesFile(path)
+
+	err := p.Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Host != "prod.internal" || config.Port != 8080 || config.Region != "eu" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	origin, ok := p.Origin("REGION")
+	if !ok || origin != filepath.Join(filepath.Dir(path), "app-eu.properties")+" (profile eu)" {
+		t.Errorf("unexpected origin for REGION: %q", origin)
+	}
+
+	origin, ok = p.Origin("PORT")
+	if !ok || origin != path {
+		t.Errorf("unexpected origin for PORT: %q", origin)
+	}
+}
+
+func TestConfigProvider_ProfileWithMissingInclude(t *testing.T) {
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties":      "HOST=base\n",
+		"app-prod.properties": "@include missing.properties\nHOST=prod\n",
+	})
+
+	config := profilesTestConfig{}
+	err := provider.NewConfigProvider().
+		FromPropertiesFile(path).
+		WithProfiles("prod").
+		Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "missing.properties") {
+		t.Errorf("expected include error from the profile file, got %v (host %q)", err, config.Host)
+	}
+}
+
+func TestConfigProvider_ProfilesFromEnvAndKey(t *testing.T) {
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties":      "HOST=localhost\nPROFILES=eu\n",
+		"app-prod.properties": "HOST=prod.internal\nREGION=us\n",
+		"app-eu.properties":   "REGION=eu\n",
+	})
+
+	t.Setenv("CONFIGPROVIDER_TEST_PROFILES", "prod")
+
+	// Profile options apply in any order, the file is read by Load
+	config := profilesTestConfig{}
+	err := provider.NewConfigProvider().
+		FromPropertiesFile(path).
+		WithProfilesFromEnv("CONFIGPROVIDER_TEST_PROFILES").
+		WithProfilesFromKey("PROFILES").
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Host != "prod.internal" || config.Region != "eu" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+}
+
+func TestConfigProvider_FromEncryptedPropertiesFile(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encryptFile := func(content string) string {
+		encrypted, err := sources.EncryptProperties([]byte(content), crypto)
+		if err != nil {
+			t.Fatalf("encryption failed: %v", err)
+		}
+		return string(encrypted)
+	}
+
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties":      encryptFile("HOST=localhost\nPORT=8080\nREGION=local\n"),
+		"app-prod.properties": encryptFile("HOST=prod.internal\n"),
+	})
+
+	config := profilesTestConfig{}
+	err = provider.NewConfigProvider().
+		WithDecrypter(crypto).
+		WithProfiles("prod").
+		FromEncryptedPropertiesFile(path).
+		Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Host != "prod.internal" || config.Port != 8080 || config.Region != "local" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	err = provider.NewConfigProvider().FromEncryptedPropertiesFile(path).Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "no decrypter is provided for encrypted file") {
+		t.Errorf("expected missing decrypter error, got %v", err)
+	}
+}
+
+func TestConfigProvider_DumpMasksEncryptedPropertiesFile(t *testing.T) {
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := sources.EncryptProperties([]byte("HOST=localhost\nREGION=hunter2\n"), crypto)
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	path := writeProfilesTestFiles(t, map[string]string{"app.properties": string(encrypted)})
+
+	config := profilesTestConfig{}
+	p := provider.NewConfigProvider().
+		FromEncryptedPropertiesFile(path).
+		WithDecrypter(crypto)
+
+	err = p.Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	dumped, err := p.Dump(&config, provider.DumpProperties)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(string(dumped), "hunter2") || strings.Contains(string(dumped), "localhost") {
+		t.Errorf("expected every value of the encrypted file to be masked, got:\n%s", dumped)
+	}
+}
+
+func TestConfigProvider_WithSignatureVerification(t *testing.T) {
+	publicKey, privateKey, err := cryptography.GenerateEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	signer, err := cryptography.NewEd25519Signer(privateKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties":      "HOST=localhost\nPORT=8080\n",
+		"app-prod.properties": "HOST=prod.internal\n",
+	})
+	prodPath := filepath.Join(filepath.Dir(path), "app-prod.properties")
+
+	for _, file := range []string{path, prodPath} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+
+		err = os.WriteFile(file+sources.SignatureSuffix, []byte(signer.Sign(sources.SignedMessage(file, data))), 0644)
+		if err != nil {
+			t.Fatalf("failed to write signature: %v", err)
+		}
+	}
+
+	config := profilesTestConfig{}
+	err = provider.NewConfigProvider().
+		WithSignatureVerification(publicKey).
+		WithProfiles("prod").
+		FromPropertiesFile(path).
+		Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "prod.internal" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	err = os.WriteFile(prodPath, []byte("HOST=attacker.example\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to tamper with file: %v", err)
+	}
+
+	err = provider.NewConfigProvider().
+		WithSignatureVerification(publicKey).
+		WithProfiles("prod").
+		FromPropertiesFile(path).
+		Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("expected signature verification error, got %v", err)
+	}
+}
+
+func TestConfigProvider_WithSignatureVerificationAfterFileSource(t *testing.T) {
+	publicKey, _, err := cryptography.GenerateEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	path := writeProfilesTestFiles(t, map[string]string{
+		"app.properties": "HOST=localhost\n",
+	})
+
+	config := profilesTestConfig{}
+	err = provider.NewConfigProvider().
+		FromPropertiesFile(path).
+		WithSignatureVerification(publicKey).
+		Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "is not signed") {
+		t.Errorf("expected unsigned file to be refused, got %v", err)
+	}
+	if config.Host != "" {
+		t.Errorf("expected no fields to be set, got %v", config)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/Reinami/configprovider/pkg/cryptography"
 	"github.com/Reinami/configprovider/pkg/sources"
@@ -15,14 +16,38 @@ type Source interface {
 }
 
 type configProvider struct {
-	source    Source
-	decrypter Decrypter
+	layers      []sourceLayer
+	source      Source // The layers opened by the last Load
+	decrypter   Decrypter
+	dispatcher  *cryptography.Dispatcher // Algorithms added with the With*Decrypter options
+	options     loadOptions
+	profiles    []string
+	profilesEnv string
+	profilesKey string
+
+	encryptedFiles bool
+	verifier       sources.Verifier
+	autoDecrypted  map[string]bool // Keys the last Load decrypted without the encrypted tag, including encrypted files
+}
+
+// sourceLayer is opened by Load, so options set anywhere in the builder chain,
+// e.g. profiles or signature verification, apply to file sources
+type sourceLayer struct {
+	name string
+	open func() (Source, error)
 }
 
-// Source options
+func staticLayer(name string, source Source) sourceLayer {
+	return sourceLayer{
+		name: name,
+		open: func() (Source, error) { return source, nil },
+	}
+}
+
+// Source options, files are read by Load
 
 func (c *configProvider) FromSource(source Source) *configProvider {
-	c.source = source
+	c.layers = []sourceLayer{staticLayer("base", source)}
 	return c
 }
 
@@ -38,32 +63,282 @@ func (c *configProvider) FromFile(path string) *configProvider {
 }
 
 func (c *configProvider) FromPropertiesFile(path string) *configProvider {
-	source, err := sources.NewPropertiesFileSource(path)
+	c.layers = []sourceLayer{c.propertiesFileLayer(path)}
+	return c
+}
+
+// FromEncryptedPropertiesFile reads a properties file where every value was
+// encrypted with lockbox encrypt-file, see sources.EncryptedSource. Fields
+// don't need the encrypted tag, their values are already decrypted.
+func (c *configProvider) FromEncryptedPropertiesFile(path string) *configProvider {
+	c.encryptedFiles = true
+	return c.FromPropertiesFile(path)
+}
+
+// FromDirectory reads one value per file, e.g. a Kubernetes secret volume or
+// Docker /run/secrets
+func (c *configProvider) FromDirectory(path string) *configProvider {
+	c.layers = []sourceLayer{directoryLayer(path)}
+	return c
+}
+
+// AddSource layers source on top of the current source, e.g. mounted secrets
+// over a properties file. name is reported by Origin.
+func (c *configProvider) AddSource(name string, source Source) *configProvider {
+	c.layers = append(c.layers, staticLayer(name, source))
+	return c
+}
+
+// AddDirectory layers a directory source on top of the current source
+func (c *configProvider) AddDirectory(path string) *configProvider {
+	c.layers = append(c.layers, directoryLayer(path))
+	return c
+}
+
+func directoryLayer(path string) sourceLayer {
+	return sourceLayer{
+		name: path,
+		open: func() (Source, error) { return sources.NewDirectorySource(path) },
+	}
+}
+
+// Profile and signature options
+
+// WithProfiles layers a profile file on top of each properties file, e.g.
+// app.properties is overridden by app-prod.properties and then app-eu.properties
+func (c *configProvider) WithProfiles(profiles ...string) *configProvider {
+	c.profiles = append(c.profiles, profiles...)
+	return c
+}
+
+// WithProfilesFromEnv reads a comma separated list of profiles from envVar
+func (c *configProvider) WithProfilesFromEnv(envVar string) *configProvider {
+	c.profilesEnv = envVar
+	return c
+}
+
+// WithProfilesFromKey reads a comma separated list of profiles from key in the
+// base file
+func (c *configProvider) WithProfilesFromKey(key string) *configProvider {
+	c.profilesKey = key
+	return c
+}
+
+// WithSignatureVerification refuses to load properties files, including
+// profile and included files, unless their detached .sig file written by
+// lockbox sign matches publicKey. Signatures are bound to the file name, not
+// to a revision, see sources.SignedMessage.
+func (c *configProvider) WithSignatureVerification(publicKey string) *configProvider {
+	verifier, err := cryptography.NewEd25519Verifier(publicKey)
 	if err != nil {
 		panic(err)
 	}
 
-	c.source = source
+	c.verifier = verifier
 	return c
 }
 
 // Decrypter options
 
+// WithDecrypter replaces the default decrypter, including algorithms added
+// before it with the other decrypter options
 func (c *configProvider) WithDecrypter(decrypter Decrypter) *configProvider {
 	c.decrypter = decrypter
+	c.dispatcher = nil
+	return c
+}
+
+// addAlgorithm adds decrypter for values whose envelope names algorithm. The
+// default decrypter is a cryptography.Dispatcher over every algorithm added,
+// so e.g. aesgcm and xchacha20poly1305 values can be mixed in one config.
+func (c *configProvider) addAlgorithm(algorithm string, decrypter Decrypter) *configProvider {
+	if c.dispatcher == nil {
+		c.dispatcher = cryptography.NewDispatcher()
+	}
+
+	c.dispatcher.Add(algorithm, decrypter)
+	c.decrypter = c.dispatcher
+	return c
+}
+
+// WithNamedDecrypter registers a decrypter for fields tagged
+// encrypted=<name>, fields tagged only encrypted keep using the default
+// decrypter
+func (c *configProvider) WithNamedDecrypter(name string, decrypter Decrypter) *configProvider {
+	if name == "" {
+		panic("named decrypter requires a name")
+	}
+
+	if c.options.namedDecrypters == nil {
+		c.options.namedDecrypters = map[string]Decrypter{}
+	}
+
+	c.options.namedDecrypters[name] = decrypter
 	return c
 }
 
+// WithAlgorithm decrypts values with the algorithm registered under name with
+// cryptography.Register, e.g. WithAlgorithm("aessiv", "base64:..."). It is used
+// for envelopes naming name, so the registered name must be the one the
+// algorithm writes in its envelopes.
+func (c *configProvider) WithAlgorithm(name string, key string) *configProvider {
+	algorithm, err := cryptography.NewAlgorithm(name, key, cryptography.AlgorithmOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	return c.addAlgorithm(name, algorithm)
+}
+
 func (c *configProvider) WithAESGCMDecrypter(secretKey string) *configProvider {
 	aesGCMDecrypter, err := cryptography.NewAESGCMCrypto(secretKey)
 	if err != nil {
 		panic(err)
 	}
 
-	c.decrypter = aesGCMDecrypter
+	return c.addAlgorithm(cryptography.AESGCMAlgorithm, aesGCMDecrypter)
+}
+
+// WithAESGCMKey loads the AES-GCM key from a cryptography.KeyLoader, e.g.
+// cryptography.KeyFromEnv("CONFIG_KEY") or cryptography.KeyFromFile(path)
+func (c *configProvider) WithAESGCMKey(loader cryptography.KeyLoader) *configProvider {
+	key, err := loader()
+	if err != nil {
+		panic(err)
+	}
+
+	return c.WithAESGCMDecrypter(string(key))
+}
+
+// WithXChaCha20Poly1305Decrypter decrypts values encrypted with
+// XChaCha20-Poly1305, see cryptography.XChaCha20Poly1305Crypto
+func (c *configProvider) WithXChaCha20Poly1305Decrypter(secretKey string) *configProvider {
+	xChaChaDecrypter, err := cryptography.NewXChaCha20Poly1305Crypto(secretKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return c.addAlgorithm(cryptography.XChaCha20Poly1305Algorithm, xChaChaDecrypter)
+}
+
+// WithAESSIVDecrypter decrypts values encrypted with deterministic AES-SIV,
+// see cryptography.AESSIVCrypto
+func (c *configProvider) WithAESSIVDecrypter(secretKey string) *configProvider {
+	aesSIVDecrypter, err := cryptography.NewAESSIVCrypto(secretKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return c.addAlgorithm(cryptography.AESSIVAlgorithm, aesSIVDecrypter)
+}
+
+// WithX25519Decrypter decrypts values encrypted to the matching public key,
+// see cryptography.X25519Crypto
+func (c *configProvider) WithX25519Decrypter(privateKey string) *configProvider {
+	x25519Decrypter, err := cryptography.NewX25519Crypto(privateKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return c.addAlgorithm(cryptography.X25519Algorithm, x25519Decrypter)
+}
+
+// WithPassphraseDecrypter decrypts values encrypted with a key derived from
+// passphrase, see cryptography.PassphraseCrypto
+func (c *configProvider) WithPassphraseDecrypter(passphrase string) *configProvider {
+	passphraseDecrypter, err := cryptography.NewPassphraseCrypto(passphrase)
+	if err != nil {
+		panic(err)
+	}
+
+	return c.addAlgorithm(cryptography.PassphraseAlgorithm, passphraseDecrypter)
+}
+
+// WithStrictEncryption fails Load when a field tagged encrypted holds a value
+// that isn't an ENC[...] envelope, catching plaintext committed by accident.
+// Legacy bare base64 AES-GCM values are refused as well.
+func (c *configProvider) WithStrictEncryption() *configProvider {
+	c.options.strictEncryption = true
+	return c
+}
+
+// WithAutoDecryption decrypts values in ENC[...] envelope form with the
+// default decrypter even when the field isn't tagged encrypted. Fields that
+// need a named decrypter must still be tagged encrypted=<name>. Use the
+// provider's Dump to mask these fields as well.
+func (c *configProvider) WithAutoDecryption() *configProvider {
+	c.options.autoDecrypt = true
+	return c
+}
+
+// WithParallelDecryption decrypts encrypted fields on up to workers goroutines
+// during Load, which helps with configs holding many encrypted values or slow
+// decrypters. The decrypters must be safe for concurrent use.
+func (c *configProvider) WithParallelDecryption(workers int) *configProvider {
+	c.options.decryptWorkers = workers
+	return c
+}
+
+// WithLazySecretTTL caches the plaintext of LazySecret fields for ttl after
+// Get decrypts it, by default every Get decrypts again
+func (c *configProvider) WithLazySecretTTL(ttl time.Duration) *configProvider {
+	c.options.lazySecretTTL = ttl
+	return c
+}
+
+// Interpolation options
+
+// WithInterpolation resolves ${...} expressions in values read from the
+// source, see interpolator. Values are used as they are without it.
+func (c *configProvider) WithInterpolation() *configProvider {
+	c.options.interpolate = true
 	return c
 }
 
+// WithDefaultInterpolation enables WithInterpolation and also resolves
+// expressions inside default= tag values
+func (c *configProvider) WithDefaultInterpolation() *configProvider {
+	c.options.interpolate = true
+	c.options.interpolateDefaults = true
+	return c
+}
+
+// File reference options
+
+// WithFileReferencePrefix reads values starting with @file: from the file
+// they reference, like fields tagged fromfile. Any source layer can then pull
+// in local files, so only use it when every layer is trusted.
+func (c *configProvider) WithFileReferencePrefix() *configProvider {
+	c.options.fileReferencePrefix = true
+	return c
+}
+
+// WithFileReferenceLimit sets the max size in bytes of files read through the
+// fromfile tag or an @file: value, the default is 1 MiB
+func (c *configProvider) WithFileReferenceLimit(limit int64) *configProvider {
+	c.options.fileSizeLimit = limit
+	return c
+}
+
+// WithStrictFilePermissions refuses to read world-readable referenced files
+func (c *configProvider) WithStrictFilePermissions() *configProvider {
+	c.options.strictFilePermissions = true
+	return c
+}
+
+// Origin reports where the value of key was loaded from by the last Load when
+// the source keeps track of it, e.g. "app-prod.properties (profile prod)"
+func (c *configProvider) Origin(key string) (string, bool) {
+	originSource, ok := c.source.(interface {
+		Origin(key string) (string, bool)
+	})
+	if !ok {
+		return "", false
+	}
+
+	return originSource.Origin(key)
+}
+
 func (c *configProvider) Load(configStruct any) error {
 	reflectValue := reflect.ValueOf(configStruct)
 
@@ -71,8 +346,46 @@ func (c *configProvider) Load(configStruct any) error {
 		return fmt.Errorf("load expects a pointer to a struct and got %T", configStruct)
 	}
 
+	// Set before the files are read, encrypted files record their keys
+	c.autoDecrypted = map[string]bool{}
+
+	source, err := c.openSource()
+	if err != nil {
+		return err
+	}
+	c.source = source
+
+	opts := c.options
+	opts.autoDecrypted = c.autoDecrypted
+
 	structValue := reflectValue.Elem()
-	return assignFields(structValue, c.source, c.decrypter)
+	return assignFields(structValue, c.source, c.decrypter, opts)
+}
+
+// Dump works like the package level Dump, and also masks fields the last Load
+// decrypted without the encrypted tag
+func (c *configProvider) Dump(cfg any, format DumpFormat) ([]byte, error) {
+	return dump(cfg, format, c.autoDecrypted)
+}
+
+// openSource opens every layer, a single layer is used as it is so its own
+// Origin is kept
+func (c *configProvider) openSource() (Source, error) {
+	if len(c.layers) == 1 {
+		return c.layers[0].open()
+	}
+
+	layered := sources.NewLayeredSource()
+	for _, layer := range c.layers {
+		source, err := layer.open()
+		if err != nil {
+			return nil, err
+		}
+
+		layered.Add(layer.name, source)
+	}
+
+	return layered, nil
 }
 
 // Constructor
@@ -1,9 +1,14 @@
 package provider_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/Reinami/configprovider/pkg/cryptography"
 	"github.com/Reinami/configprovider/pkg/provider"
+	"github.com/Reinami/configprovider/pkg/sources"
 )
 
 type mockSource map[string]string
@@ -96,3 +101,380 @@ func TestConfigProvider_Load(t *testing.T) {
 		t.Errorf("unsettable mismatch: expected %v, got %v", "unsettable", config.GetUnsettable())
 	}
 }
+
+func TestConfigProvider_AddDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	propertiesPath := filepath.Join(dir, "app.properties")
+	err := os.WriteFile(propertiesPath, []byte("APP_NAME=TestService\nDEBUG=false\nSECRET=plain\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write properties file: %v", err)
+	}
+
+	secretsDir := filepath.Join(dir, "secrets")
+	err = os.Mkdir(secretsDir, 0755)
+	if err != nil {
+		t.Fatalf("failed to create secrets dir: %v", err)
+	}
+	for name, content := range map[string]string{"DEBUG": "true\n", "SECRET": "encrypted\n"} {
+		err = os.WriteFile(filepath.Join(secretsDir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("failed to write secret %s: %v", name, err)
+		}
+	}
+
+	config := mockConfig{}
+	p := provider.NewConfigProvider().
+		FromPropertiesFile(propertiesPath).
+		AddDirectory(secretsDir).
+		WithDecrypter(&mockDecrypter{Value: "decrypted"})
+
+	err = p.Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.AppName != "TestService" || config.Debug != true || config.SecretKey != "decrypted" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	origin, _ := p.Origin("SECRET")
+	if origin != secretsDir {
+		t.Errorf("expected SECRET to come from %q, got %q", secretsDir, origin)
+	}
+
+	origin, _ = p.Origin("APP_NAME")
+	if origin != propertiesPath {
+		t.Errorf("expected APP_NAME to come from %q, got %q", propertiesPath, origin)
+	}
+}
+
+func TestConfigProvider_AddSourceKeepsCallerSource(t *testing.T) {
+	layered := sources.NewLayeredSource().
+		Add("defaults", mockSource{"APP_NAME": "TestService", "DEBUG": "false"})
+
+	config := mockConfig{}
+	p := provider.NewConfigProvider().
+		FromSource(layered).
+		AddSource("overrides", mockSource{"DEBUG": "true"})
+
+	err := p.Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.AppName != "TestService" || config.Debug != true {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	value, _ := layered.Get("DEBUG")
+	if value != "false" {
+		t.Errorf("expected the caller's source to be left alone, got DEBUG=%s", value)
+	}
+
+	origin, _ := p.Origin("APP_NAME")
+	if origin != "defaults" {
+		t.Errorf("expected APP_NAME to come from the nested layer, got %q", origin)
+	}
+}
+
+func TestConfigProvider_WithPassphraseDecrypter(t *testing.T) {
+	crypto, err := cryptography.NewPassphraseCrypto("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.WithIterations(1000).EncryptFor("SECRET", "decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithPassphraseDecrypter("correct horse battery staple").
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_WithAESGCMKey(t *testing.T) {
+	const key = "12345678901234567890123456789012"
+
+	crypto, err := cryptography.NewAESGCMCrypto(key)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	t.Setenv("CONFIGPROVIDER_TEST_KEY", "base64:MTIzNDU2Nzg5MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTI=")
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithAESGCMKey(cryptography.KeyFromEnv("CONFIGPROVIDER_TEST_KEY")).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_WithX25519Decrypter(t *testing.T) {
+	publicKey, privateKey, err := cryptography.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypter, err := cryptography.NewX25519Encrypter(publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := encrypter.Encrypt("decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithX25519Decrypter(privateKey).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_WithAESSIVDecrypter(t *testing.T) {
+	const key = "1234567890123456789012345678901234567890123456789012345678901234"
+
+	crypto, err := cryptography.NewAESSIVCrypto(key)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.EncryptFor("SECRET", "decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithAESSIVDecrypter(key).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_WithNamedDecrypter(t *testing.T) {
+	legacy, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	current, err := cryptography.NewAESSIVCrypto("abcdefghijklmnopqrstuvwxyz012345")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	legacyValue, err := legacy.Encrypt("legacy secret")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	currentValue, err := current.Encrypt("current secret")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	type namedConfig struct {
+		Current string `config:"CURRENT,encrypted"`
+		Legacy  string `config:"LEGACY,encrypted=legacy"`
+	}
+
+	config := namedConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"CURRENT": currentValue, "LEGACY": legacyValue}).
+		WithDecrypter(current).
+		WithNamedDecrypter("legacy", legacy).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.Current != "current secret" || config.Legacy != "legacy secret" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+}
+
+func TestConfigProvider_AutoDecryptsUntaggedEnvelope(t *testing.T) {
+	const key = "12345678901234567890123456789012"
+
+	crypto, err := cryptography.NewAESGCMCrypto(key)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("TestService")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	p := provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": encrypted, "DEBUG": "true", "SECRET": encrypted}).
+		WithAESGCMDecrypter(key).
+		WithAutoDecryption().
+		WithStrictEncryption()
+
+	err = p.Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.AppName != "TestService" {
+		t.Errorf("AppName mismatch: expected %v, got %v", "TestService", config.AppName)
+	}
+
+	dumped, err := p.Dump(&config, provider.DumpProperties)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(dumped), "TestService") || !strings.Contains(string(dumped), "APP_NAME=****") {
+		t.Errorf("expected auto decrypted APP_NAME to be masked, got:\n%s", dumped)
+	}
+}
+
+func TestConfigProvider_WithAlgorithm(t *testing.T) {
+	const key = "12345678901234567890123456789012"
+
+	crypto, err := cryptography.NewAESSIVCrypto(key)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithAlgorithm(cryptography.AESSIVAlgorithm, key).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_WithAlgorithm_Unsupported(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithAlgorithm to panic for an unknown algorithm")
+		}
+	}()
+
+	provider.NewConfigProvider().WithAlgorithm("does-not-exist", "key")
+}
+
+func TestConfigProvider_WithXChaCha20Poly1305Decrypter(t *testing.T) {
+	const key = "12345678901234567890123456789012"
+
+	crypto, err := cryptography.NewXChaCha20Poly1305Crypto(key)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	encrypted, err := crypto.Encrypt("decrypted")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	config := mockConfig{}
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"APP_NAME": "TestService", "DEBUG": "true", "SECRET": encrypted}).
+		WithXChaCha20Poly1305Decrypter(key).
+		Load(&config)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.SecretKey != "decrypted" {
+		t.Errorf("SecretKey mismatch: expected %v, got %v", "decrypted", config.SecretKey)
+	}
+}
+
+func TestConfigProvider_DispatchesOnEnvelopeAlgorithm(t *testing.T) {
+	const aesGCMKey = "12345678901234567890123456789012"
+	const xChaChaKey = "abcdefghijklmnopqrstuvwxyz123456"
+
+	aesGCM, _ := cryptography.NewAESGCMCrypto(aesGCMKey)
+	xChaCha, _ := cryptography.NewXChaCha20Poly1305Crypto(xChaChaKey)
+
+	aesGCMValue, _ := aesGCM.Encrypt("from-aesgcm")
+	xChaChaValue, _ := xChaCha.Encrypt("from-xchacha")
+
+	type dispatchConfig struct {
+		First  string `config:"FIRST,encrypted"`
+		Second string `config:"SECOND,encrypted"`
+	}
+
+	config := dispatchConfig{}
+	err := provider.NewConfigProvider().
+		FromSource(mockSource{"FIRST": aesGCMValue, "SECOND": xChaChaValue}).
+		WithAESGCMDecrypter(aesGCMKey).
+		WithXChaCha20Poly1305Decrypter(xChaChaKey).
+		Load(&config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.First != "from-aesgcm" || config.Second != "from-xchacha" {
+		t.Errorf("got incorrect fields on config %v", config)
+	}
+
+	// WithDecrypter replaces the algorithms added before it
+	err = provider.NewConfigProvider().
+		FromSource(mockSource{"FIRST": aesGCMValue, "SECOND": xChaChaValue}).
+		WithAESGCMDecrypter(aesGCMKey).
+		WithDecrypter(&mockDecrypter{Value: "mock"}).
+		Load(&config)
+	if err != nil || config.First != "mock" || config.Second != "mock" {
+		t.Errorf("expected the mock decrypter to be used, got %v, %v", config, err)
+	}
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+)
+
+const redacted = "****"
+
+// Secret holds a config value that must not end up in logs. Printing it with
+// fmt, encoding it to JSON or logging it with slog renders "****", the value
+// is only available through Reveal.
+//
+//	type Config struct {
+//		DBPassword provider.SecretString `config:"DB_PASSWORD,encrypted"`
+//		APIPort    provider.Secret[int]  `config:"API_PORT"`
+//	}
+type Secret[T any] struct {
+	raw []byte
+}
+
+// SecretString is a Secret holding a string
+type SecretString = Secret[string]
+
+// NewSecret creates a Secret from its plaintext representation, e.g. "8080"
+// for a Secret[int]
+func NewSecret[T any](rawValue string) (Secret[T], error) {
+	var secret Secret[T]
+	err := secret.setSecret(rawValue)
+	return secret, err
+}
+
+// Reveal returns the plaintext value, or the zero value after Wipe
+func (s Secret[T]) Reveal() T {
+	var value T
+	if len(s.raw) == 0 {
+		return value
+	}
+
+	// The raw value is validated when the secret is set, so this can't fail
+	_ = parseAndSetValue(reflect.ValueOf(&value).Elem(), string(s.raw))
+	return value
+}
+
+// Wipe zeroes the bytes backing the secret. Copies of the Secret share these
+// bytes and are wiped as well, values already returned by Reveal are not.
+func (s *Secret[T]) Wipe() {
+	clear(s.raw)
+	s.raw = nil
+}
+
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+func (s Secret[T]) GoString() string {
+	return redacted
+}
+
+func (s Secret[T]) Format(f fmt.State, _ rune) {
+	_, _ = io.WriteString(f, redacted)
+}
+
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (s *Secret[T]) setSecret(rawValue string) error {
+	var value T
+	err := parseAndSetValue(reflect.ValueOf(&value).Elem(), rawValue)
+	if err != nil {
+		return err
+	}
+
+	s.raw = []byte(rawValue)
+	return nil
+}
+
+// secretSetter is implemented by *Secret[T] so parseAndSetValue can set
+// secrets of any type
+type secretSetter interface {
+	setSecret(rawValue string) error
+}
+
+func asSecretSetter(field reflect.Value) (secretSetter, bool) {
+	if !field.CanAddr() {
+		return nil, false
+	}
+
+	setter, ok := field.Addr().Interface().(secretSetter)
+	return setter, ok
+}
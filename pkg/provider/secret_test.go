@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mockSecretTestConfig struct {
+	Password SecretString   `config:"PASSWORD,encrypted"`
+	Port     Secret[int]    `config:"PORT"`
+	Tokens   []SecretString `config:"TOKENS"`
+}
+
+func loadSecretTestConfig(t *testing.T) mockSecretTestConfig {
+	t.Helper()
+
+	source := mockParseTestSource{
+		"PASSWORD": "ciphertext",
+		"PORT":     "8080",
+		"TOKENS":   "a,b",
+	}
+
+	config := mockSecretTestConfig{}
+	err := assignFields(reflect.ValueOf(&config).Elem(), source, &mockParseTestDecrypter{Value: "hunter2"}, loadOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return config
+}
+
+func TestSecret_AssignAndReveal(t *testing.T) {
+	config := loadSecretTestConfig(t)
+
+	if config.Password.Reveal() != "hunter2" {
+		t.Errorf("expected revealed password, got %q", config.Password.Reveal())
+	}
+	if config.Port.Reveal() != 8080 {
+		t.Errorf("expected revealed port, got %d", config.Port.Reveal())
+	}
+	if len(config.Tokens) != 2 || config.Tokens[1].Reveal() != "b" {
+		t.Errorf("expected revealed tokens, got %v", config.Tokens)
+	}
+}
+
+func TestSecret_Redaction(t *testing.T) {
+	config := loadSecretTestConfig(t)
+
+	outputs := []string{
+		fmt.Sprintf("%v", config),
+		fmt.Sprintf("%+v", config),
+		fmt.Sprintf("%#v", config),
+		fmt.Sprintf("%s %q %d", config.Password, config.Password, config.Port),
+		config.Password.String(),
+		config.Password.GoString(),
+	}
+
+	jsonOutput, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputs = append(outputs, string(jsonOutput))
+
+	var logOutput bytes.Buffer
+	slog.New(slog.NewTextHandler(&logOutput, nil)).Info("config", "password", config.Password, "port", config.Port)
+	outputs = append(outputs, logOutput.String())
+
+	for _, output := range outputs {
+		if strings.Contains(output, "hunter2") || strings.Contains(output, "8080") {
+			t.Errorf("secret leaked in %q", output)
+		}
+		if !strings.Contains(output, redacted) {
+			t.Errorf("expected %q in %q", redacted, output)
+		}
+	}
+}
+
+func TestSecret_Wipe(t *testing.T) {
+	config := loadSecretTestConfig(t)
+
+	backing := config.Password.raw
+	copied := config.Password
+
+	config.Password.Wipe()
+
+	if config.Password.Reveal() != "" {
+		t.Errorf("expected wiped secret to reveal zero value, got %q", config.Password.Reveal())
+	}
+	if !bytes.Equal(backing, make([]byte, len(backing))) {
+		t.Errorf("expected backing bytes to be zeroed, got %v", backing)
+	}
+	if copied.Reveal() == "hunter2" {
+		t.Error("expected copies to share the wiped bytes")
+	}
+}
+
+func TestSecret_ParseError(t *testing.T) {
+	_, err := NewSecret[int]("notanint")
+	if err == nil || !strings.Contains(err.Error(), "unable to parse int") {
+		t.Errorf("expected parse error but got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package sources
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DirectorySource reads one value per file, using the file name as the key.
+// This is the layout of Kubernetes secret volumes and Docker /run/secrets.
+// Entries starting with a dot, such as the Kubernetes ..data symlink and its
+// timestamped target directory, are ignored.
+type DirectorySource struct {
+	path   string
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+func NewDirectorySource(path string) (*DirectorySource, error) {
+	source := &DirectorySource{path: path}
+
+	err := source.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// Reload re-reads every file in the directory. Keys are symlinks through
+// ..data, which Kubernetes swaps atomically on update, so a reload after a swap
+// sees a consistent set of new values.
+func (s *DirectorySource) Reload() error {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		filePath := filepath.Join(s.path, name)
+
+		// Stat follows symlinks so key -> ..data/key resolves to the file
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", filePath, err)
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+
+		values[name] = TrimTrailingNewline(string(content))
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *DirectorySource) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.values[key]
+	return val, ok
+}
+
+// File returns the file holding the value of key
+func (s *DirectorySource) File(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.values[key]
+	if !ok {
+		return "", false
+	}
+
+	return filepath.Join(s.path, key), true
+}
+
+// TrimTrailingNewline drops one trailing line ending, which most editors add
+// to files holding a single value
+func TrimTrailingNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
@@ -0,0 +1,117 @@
+package sources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTmpDirectory(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestNewDirectorySource_Files(t *testing.T) {
+	dir := t.TempDir()
+	writeTmpDirectory(t, dir, map[string]string{
+		"DB_PASSWORD": "hunter2\n",
+		"API_TOKEN":   "token\r\n",
+		"MULTILINE":   "line1\nline2\n\n",
+		".hidden":     "ignored",
+	})
+
+	err := os.Mkdir(filepath.Join(dir, "subdir"), 0755)
+	if err != nil {
+		t.Fatalf("failed to create subdir: %v", err)
+	}
+
+	source, err := NewDirectorySource(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	tests := map[string]string{
+		"DB_PASSWORD": "hunter2",
+		"API_TOKEN":   "token",
+		"MULTILINE":   "line1\nline2\n",
+	}
+
+	for key, expected := range tests {
+		got, ok := source.Get(key)
+		if !ok || got != expected {
+			t.Errorf("key %q: expected %q, got %q", key, expected, got)
+		}
+	}
+
+	for _, key := range []string{".hidden", "subdir"} {
+		_, ok := source.Get(key)
+		if ok {
+			t.Errorf("expected key %q to be ignored", key)
+		}
+	}
+}
+
+func TestDirectorySource_ReloadFollowsDataSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+
+	// Mimic the Kubernetes secret volume layout:
+	// KEY -> ..data/KEY, ..data -> ..2026_01_01
+	writeTmpDirectory(t, filepath.Join(dir, "..2026_01_01"), map[string]string{"KEY": "old\n"})
+	writeTmpDirectory(t, filepath.Join(dir, "..2026_02_01"), map[string]string{"KEY": "new\n"})
+
+	err := os.Symlink("..2026_01_01", filepath.Join(dir, "..data"))
+	if err != nil {
+		t.Fatalf("failed to create ..data symlink: %v", err)
+	}
+	err = os.Symlink(filepath.Join("..data", "KEY"), filepath.Join(dir, "KEY"))
+	if err != nil {
+		t.Fatalf("failed to create key symlink: %v", err)
+	}
+
+	source, err := NewDirectorySource(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	got, _ := source.Get("KEY")
+	if got != "old" {
+		t.Fatalf("expected %q, got %q", "old", got)
+	}
+
+	err = os.Symlink("..2026_02_01", filepath.Join(dir, "..data_tmp"))
+	if err != nil {
+		t.Fatalf("failed to create ..data_tmp symlink: %v", err)
+	}
+	err = os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+	if err != nil {
+		t.Fatalf("failed to swap ..data symlink: %v", err)
+	}
+
+	err = source.Reload()
+	if err != nil {
+		t.Fatalf("expected no error on reload, got: %v", err)
+	}
+
+	got, _ = source.Get("KEY")
+	if got != "new" {
+		t.Errorf("expected %q after reload, got %q", "new", got)
+	}
+}
+
+func TestNewDirectorySource_NotFound(t *testing.T) {
+	_, err := NewDirectorySource("nonexistent/dir")
+	if err == nil {
+		t.Fatalf("expected directory not found error, got none")
+	}
+}
@@ -0,0 +1,293 @@
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
+)
+
+// MACKey is the trailer key holding the encrypted MAC of a whole-file
+// encrypted properties file
+const MACKey = "configprovider.mac"
+
+// KeyedSource is a Source that can list its keys in a stable order, e.g.
+// PropertiesSource
+type KeyedSource interface {
+	Source
+	Keys() []string
+}
+
+// EncryptedSource decrypts every value of a whole-file encrypted source, as
+// written by EncryptProperties. Keys stay readable for review, values are
+// bound to their key and a MAC over all pairs is stored under MACKey, so
+// moving, reordering, adding or removing entries is detected.
+type EncryptedSource struct {
+	values map[string]string
+	keys   []string
+	source KeyedSource
+}
+
+// NewEncryptedSource decrypts and verifies every value of source up front
+func NewEncryptedSource(source KeyedSource, decrypter cryptography.Decrypter) (*EncryptedSource, error) {
+	if decrypter == nil {
+		return nil, errors.New("no decrypter is provided for the encrypted source")
+	}
+
+	encryptedSource := &EncryptedSource{values: map[string]string{}, source: source}
+	var encryptedMAC string
+
+	for _, key := range source.Keys() {
+		value, _ := source.Get(key)
+		if key == MACKey {
+			encryptedMAC = value
+			continue
+		}
+
+		plainText, err := decryptFor(decrypter, key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		encryptedSource.keys = append(encryptedSource.keys, key)
+		encryptedSource.values[key] = plainText
+	}
+
+	err := verifyMAC(decrypter, encryptedMAC, encryptedSource.keys, encryptedSource.values)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedSource, nil
+}
+
+func (s *EncryptedSource) Get(key string) (string, bool) {
+	val, ok := s.values[key]
+	return val, ok
+}
+
+// File returns the file that defines key when the wrapped source is a
+// FileSource
+func (s *EncryptedSource) File(key string) (string, bool) {
+	fileSource, ok := s.source.(FileSource)
+	if !ok {
+		return "", false
+	}
+
+	return fileSource.File(key)
+}
+
+// Keys returns every key except MACKey in file order
+func (s *EncryptedSource) Keys() []string {
+	return append([]string{}, s.keys...)
+}
+
+// EncryptProperties encrypts every value of a properties file, keeping keys,
+// comments and blank lines as they are, and appends the MACKey trailer.
+// Include directives are refused since the included values would not be
+// covered by the MAC.
+func EncryptProperties(data []byte, encrypter cryptography.Encrypter) ([]byte, error) {
+	var output bytes.Buffer
+	var keys []string
+	values := map[string]string{}
+
+	err := transformProperties(data, &output, func(key string, value string) (string, bool, error) {
+		if key == MACKey {
+			return "", false, fmt.Errorf("file already contains %s, is it already encrypted?", MACKey)
+		}
+
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = value
+
+		cipherText, err := encryptFor(encrypter, key, value)
+		return cipherText, true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := encryptFor(encrypter, MACKey, propertiesDigest(keys, values))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkSymmetricMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&output, "%s=%s\n", MACKey, mac)
+	return output.Bytes(), nil
+}
+
+// DecryptProperties reverses EncryptProperties, verifying the MACKey trailer
+// and dropping it from the output
+func DecryptProperties(data []byte, decrypter cryptography.Decrypter) ([]byte, error) {
+	var output bytes.Buffer
+	var keys []string
+	var encryptedMAC string
+	values := map[string]string{}
+
+	err := transformProperties(data, &output, func(key string, value string) (string, bool, error) {
+		if key == MACKey {
+			encryptedMAC = value
+			return "", false, nil
+		}
+
+		plainText, err := decryptFor(decrypter, key, value)
+		if err != nil {
+			return "", false, err
+		}
+
+		if _, exists := values[key]; !exists {
+			keys = append(keys, key)
+		}
+		values[key] = plainText
+
+		return plainText, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyMAC(decrypter, encryptedMAC, keys, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Bytes(), nil
+}
+
+// transformProperties copies data to output line by line, replacing every
+// value with the result of transform. Lines transform doesn't keep are
+// dropped.
+func transformProperties(data []byte, output *bytes.Buffer, transform func(key string, value string) (string, bool, error)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		rawLine := scanner.Text()
+		line := strings.TrimSpace(rawLine)
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			output.WriteString(rawLine + "\n")
+			continue
+		}
+
+		_, _, isInclude := parseIncludeDirective(line)
+		if isInclude {
+			return fmt.Errorf("encrypted properties files can't use include directives: %s", line)
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("malformed line: %s", line)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		newValue, keep, err := transform(key, value)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+
+		fmt.Fprintf(output, "%s=%s\n", key, newValue)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to parse properties file %w", err)
+	}
+
+	return nil
+}
+
+// propertiesDigest hashes the key=value pairs in order, keys can't contain
+// "=" and values can't contain newlines so the encoding is unambiguous
+func propertiesDigest(keys []string, values map[string]string) string {
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, values[key])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func verifyMAC(decrypter cryptography.Decrypter, encryptedMAC string, keys []string, values map[string]string) error {
+	if encryptedMAC == "" {
+		return fmt.Errorf("encrypted properties are missing the %s trailer", MACKey)
+	}
+
+	err := checkSymmetricMAC(encryptedMAC)
+	if err != nil {
+		return err
+	}
+
+	mac, err := decryptFor(decrypter, MACKey, encryptedMAC)
+	if err != nil {
+		return err
+	}
+
+	expected := propertiesDigest(keys, values)
+	if subtle.ConstantTimeCompare([]byte(mac), []byte(expected)) != 1 {
+		return fmt.Errorf("%s does not match, entries were added, removed or reordered", MACKey)
+	}
+
+	return nil
+}
+
+// checkSymmetricMAC refuses MACs written with a public key algorithm, anyone
+// holding the public key could write a matching trailer for edited entries
+func checkSymmetricMAC(encryptedMAC string) error {
+	envelope, err := cryptography.ParseEnvelope(encryptedMAC)
+	if err != nil || !cryptography.IsPublicKeyAlgorithm(envelope.Algorithm) {
+		return nil
+	}
+
+	return fmt.Errorf("encrypted properties need a symmetric algorithm, anyone with the %s public key could forge the %s trailer", envelope.Algorithm, MACKey)
+}
+
+func encryptFor(encrypter cryptography.Encrypter, key string, plainText string) (string, error) {
+	var cipherText string
+	var err error
+
+	keyAwareEncrypter, ok := encrypter.(cryptography.KeyAwareEncrypter)
+	if ok {
+		cipherText, err = keyAwareEncrypter.EncryptFor(key, plainText)
+	} else {
+		cipherText, err = encrypter.Encrypt(plainText)
+	}
+	if err != nil {
+		return "", fmt.Errorf("encryption failed for %s: %w", key, err)
+	}
+
+	return cipherText, nil
+}
+
+func decryptFor(decrypter cryptography.Decrypter, key string, cipherText string) (string, error) {
+	var plainText string
+	var err error
+
+	keyAwareDecrypter, ok := decrypter.(cryptography.KeyAwareDecrypter)
+	if ok {
+		plainText, err = keyAwareDecrypter.DecryptFor(key, cipherText)
+	} else {
+		plainText, err = decrypter.Decrypt(cipherText)
+	}
+	if err != nil {
+		return "", fmt.Errorf("decryption failed for %s: %w", key, err)
+	}
+
+	return plainText, nil
+}
@@ -0,0 +1,204 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
+)
+
+const encryptedTestProperties = `# database settings
+DB.HOST=db.internal
+DB.PASSWORD=hunter2
+
+FEATURE.FLAGS={"beta":true}
+`
+
+func newEncryptedTestCrypto(t *testing.T) *cryptography.AESGCMCrypto {
+	t.Helper()
+
+	crypto, err := cryptography.NewAESGCMCrypto("12345678901234567890123456789012")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	return crypto
+}
+
+func encryptTestProperties(t *testing.T, crypto *cryptography.AESGCMCrypto) string {
+	t.Helper()
+
+	encrypted, err := EncryptProperties([]byte(encryptedTestProperties), crypto)
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	return string(encrypted)
+}
+
+func newEncryptedTestSource(t *testing.T, content string, crypto *cryptography.AESGCMCrypto) (*EncryptedSource, error) {
+	t.Helper()
+
+	source, err := NewPropertiesFileSource(writeTmpProperties(t, content))
+	if err != nil {
+		t.Fatalf("failed to read properties: %v", err)
+	}
+
+	return NewEncryptedSource(source, crypto)
+}
+
+func TestEncryptProperties_KeepsKeysAndComments(t *testing.T) {
+	encrypted := encryptTestProperties(t, newEncryptedTestCrypto(t))
+
+	lines := strings.Split(encrypted, "\n")
+	if lines[0] != "# database settings" || lines[3] != "" {
+		t.Errorf("expected comments and blank lines to be kept, got:\n%s", encrypted)
+	}
+	if !strings.HasPrefix(lines[1], "DB.HOST=ENC[aesgcm,") || strings.Contains(encrypted, "hunter2") {
+		t.Errorf("expected encrypted values with readable keys, got:\n%s", encrypted)
+	}
+	if !strings.HasPrefix(lines[5], MACKey+"=ENC[") {
+		t.Errorf("expected MAC trailer, got:\n%s", encrypted)
+	}
+
+	_, err := EncryptProperties([]byte(encrypted), newEncryptedTestCrypto(t))
+	if err == nil || !strings.Contains(err.Error(), "already encrypted") {
+		t.Errorf("expected error re-encrypting an encrypted file, got %v", err)
+	}
+}
+
+func TestDecryptProperties_RoundTrip(t *testing.T) {
+	crypto := newEncryptedTestCrypto(t)
+
+	decrypted, err := DecryptProperties([]byte(encryptTestProperties(t, crypto)), crypto)
+	if err != nil {
+		t.Fatalf("decryption failed: %v", err)
+	}
+
+	if string(decrypted) != encryptedTestProperties {
+		t.Errorf("expected:\n%s\ngot:\n%s", encryptedTestProperties, decrypted)
+	}
+}
+
+func TestEncryptedSource_DecryptsAllValues(t *testing.T) {
+	crypto := newEncryptedTestCrypto(t)
+
+	source, err := newEncryptedTestSource(t, encryptTestProperties(t, crypto), crypto)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	value, ok := source.Get("DB.PASSWORD")
+	if !ok || value != "hunter2" {
+		t.Errorf("expected decrypted value, got %q, %v", value, ok)
+	}
+
+	if _, ok := source.Get(MACKey); ok {
+		t.Error("expected MAC trailer to be hidden")
+	}
+
+	keys := strings.Join(source.Keys(), ",")
+	if keys != "DB.HOST,DB.PASSWORD,FEATURE.FLAGS" {
+		t.Errorf("expected keys in file order, got %s", keys)
+	}
+}
+
+func TestEncryptedSource_DetectsTampering(t *testing.T) {
+	crypto := newEncryptedTestCrypto(t)
+	encrypted := encryptTestProperties(t, crypto)
+	lines := strings.Split(encrypted, "\n")
+
+	swapValue := func(line string, key string) string {
+		_, value, _ := strings.Cut(line, "=")
+		return key + "=" + value
+	}
+
+	tests := map[string]struct {
+		content  string
+		expected string
+	}{
+		"removed entry": {
+			content:  strings.Replace(encrypted, lines[2]+"\n", "", 1),
+			expected: "does not match",
+		},
+		"reordered entries": {
+			content:  strings.Join([]string{lines[0], lines[2], lines[1], lines[3], lines[4], lines[5]}, "\n"),
+			expected: "does not match",
+		},
+		"moved value": {
+			content:  strings.Replace(encrypted, lines[1], swapValue(lines[2], "DB.HOST"), 1),
+			expected: "decryption failed for DB.HOST",
+		},
+		"missing trailer": {
+			content:  strings.Replace(encrypted, lines[5], "", 1),
+			expected: "missing the " + MACKey,
+		},
+		"plaintext entry": {
+			content:  encrypted + "EXTRA=plaintext\n",
+			expected: "decryption failed for EXTRA",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := newEncryptedTestSource(t, test.content, crypto)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", name, test.expected, err)
+		}
+
+		_, err = DecryptProperties([]byte(test.content), crypto)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: expected DecryptProperties error containing %q, got %v", name, test.expected, err)
+		}
+	}
+}
+
+func TestEncryptProperties_RefusesIncludes(t *testing.T) {
+	_, err := EncryptProperties([]byte("@include common.properties\nKEY=value\n"), newEncryptedTestCrypto(t))
+	if err == nil || !strings.Contains(err.Error(), "can't use include directives") {
+		t.Errorf("expected include error, got %v", err)
+	}
+}
+
+func TestPropertiesSource_Keys(t *testing.T) {
+	path := writeTmpProperties(t, "B=1\nA=2\nB=3\n")
+
+	source, err := NewPropertiesFileSource(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := strings.Join(source.Keys(), ",")
+	if keys != "B,A" {
+		t.Errorf("expected keys in order of first appearance, got %s", keys)
+	}
+}
+
+func TestEncryptedProperties_RefusesPublicKeyAlgorithms(t *testing.T) {
+	publicKey, _, err := cryptography.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	encrypter, err := cryptography.NewX25519Encrypter(publicKey)
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	_, err = EncryptProperties([]byte(encryptedTestProperties), encrypter)
+	if err == nil || !strings.Contains(err.Error(), "need a symmetric algorithm") {
+		t.Errorf("expected public key algorithm error, got %v", err)
+	}
+
+	crypto := newEncryptedTestCrypto(t)
+	forgedMAC, err := encrypter.EncryptFor(MACKey, "digest")
+	if err != nil {
+		t.Fatalf("encryption failed: %v", err)
+	}
+
+	lines := strings.Split(encryptTestProperties(t, crypto), "\n")
+	lines[5] = MACKey + "=" + forgedMAC
+	_, err = newEncryptedTestSource(t, strings.Join(lines, "\n"), crypto)
+	if err == nil || !strings.Contains(err.Error(), "need a symmetric algorithm") {
+		t.Errorf("expected public key algorithm error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package sources
+
+// Source is implemented by every source in this package.
+type Source interface {
+	Get(key string) (string, bool)
+}
+
+type layer struct {
+	name   string
+	source Source
+}
+
+// LayeredSource resolves keys against a stack of sources where later layers
+// override earlier ones. Each layer is named so callers can find out where a
+// value came from.
+type LayeredSource struct {
+	layers []layer
+}
+
+func NewLayeredSource() *LayeredSource {
+	return &LayeredSource{}
+}
+
+func (s *LayeredSource) Add(name string, source Source) *LayeredSource {
+	s.layers = append(s.layers, layer{name: name, source: source})
+	return s
+}
+
+func (s *LayeredSource) Get(key string) (string, bool) {
+	_, val, ok := s.lookup(key)
+	return val, ok
+}
+
+// Origin returns the name of the layer that provides key, or the origin
+// reported by that layer when it keeps track of its own, e.g. a nested
+// LayeredSource.
+func (s *LayeredSource) Origin(key string) (string, bool) {
+	found, _, ok := s.lookup(key)
+	if !ok {
+		return "", false
+	}
+
+	originSource, isOriginSource := found.source.(interface {
+		Origin(key string) (string, bool)
+	})
+	if isOriginSource {
+		origin, ok := originSource.Origin(key)
+		if ok {
+			return origin, true
+		}
+	}
+
+	return found.name, true
+}
+
+// File returns the file that defines key when the layer providing it is a
+// FileSource
+func (s *LayeredSource) File(key string) (string, bool) {
+	found, _, ok := s.lookup(key)
+	if !ok {
+		return "", false
+	}
+
+	fileSource, ok := found.source.(FileSource)
+	if !ok {
+		return "", false
+	}
+
+	return fileSource.File(key)
+}
+
+func (s *LayeredSource) lookup(key string) (layer, string, bool) {
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		val, ok := s.layers[i].source.Get(key)
+		if ok {
+			return s.layers[i], val, true
+		}
+	}
+
+	return layer{}, "", false
+}
@@ -0,0 +1,63 @@
+package sources
+
+import (
+	"testing"
+)
+
+type mockLayeredTestSource map[string]string
+
+func (m mockLayeredTestSource) Get(key string) (string, bool) {
+	val, ok := m[key]
+	return val, ok
+}
+
+func TestLayeredSource_LaterLayersOverride(t *testing.T) {
+	source := NewLayeredSource().
+		Add("base", mockLayeredTestSource{"HOST": "localhost", "PORT": "8080"}).
+		Add("prod", mockLayeredTestSource{"HOST": "prod.internal"})
+
+	tests := map[string][2]string{
+		"HOST": {"prod.internal", "prod"},
+		"PORT": {"8080", "base"},
+	}
+
+	for key, expected := range tests {
+		got, ok := source.Get(key)
+		if !ok || got != expected[0] {
+			t.Errorf("key %q: expected %q, got %q (found %v)", key, expected[0], got, ok)
+		}
+
+		origin, ok := source.Origin(key)
+		if !ok || origin != expected[1] {
+			t.Errorf("key %q: expected origin %q, got %q (found %v)", key, expected[1], origin, ok)
+		}
+	}
+
+	_, ok := source.Get("MISSING")
+	if ok {
+		t.Errorf("expected MISSING to not be found")
+	}
+}
+
+func TestLayeredSource_NestedOrigin(t *testing.T) {
+	nested := NewLayeredSource().
+		Add("app.properties", mockLayeredTestSource{"HOST": "localhost"}).
+		Add("app-prod.properties", mockLayeredTestSource{"PORT": "443"})
+
+	source := NewLayeredSource().
+		Add("base", nested).
+		Add("secrets", mockLayeredTestSource{"PASSWORD": "hunter2"})
+
+	tests := map[string]string{
+		"HOST":     "app.properties",
+		"PORT":     "app-prod.properties",
+		"PASSWORD": "secrets",
+	}
+
+	for key, expected := range tests {
+		origin, ok := source.Origin(key)
+		if !ok || origin != expected {
+			t.Errorf("key %q: expected origin %q, got %q (found %v)", key, expected, origin, ok)
+		}
+	}
+}
@@ -2,24 +2,103 @@ package sources
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// SignatureSuffix is appended to a file's path to find its detached
+// signature, e.g. app.properties.sig
+const SignatureSuffix = ".sig"
+
+// signatureContext keeps signatures of properties files apart from anything
+// else signed with the same key
+const signatureContext = "configprovider properties signature v1\n"
+
+// Verifier checks the detached signature of a file, e.g.
+// cryptography.Ed25519Verifier
+type Verifier interface {
+	Verify(data []byte, signature string) error
+}
+
+// FileSource is a Source that knows which file defines each key, so relative
+// paths in values can be resolved against that file
+type FileSource interface {
+	Source
+	File(key string) (string, bool)
+}
+
 type PropertiesSource struct {
-	values map[string]string
+	values   map[string]string
+	keys     []string
+	files    map[string]string
+	verifier Verifier
 }
 
-func NewPropertiesFileSource(path string) (*PropertiesSource, error) {
-	file, err := os.Open(path)
+type PropertiesOption func(*PropertiesSource)
+
+// WithSignatureVerifier refuses to read the file, or any file it includes,
+// unless the signature in the file's .sig sibling matches its contents
+func WithSignatureVerifier(verifier Verifier) PropertiesOption {
+	return func(s *PropertiesSource) {
+		s.verifier = verifier
+	}
+}
+
+// NewPropertiesFileSource parses a properties file. Other files can be pulled
+// in with an include directive, resolved relative to the including file:
+//
+//	@include common.properties
+//	@include? local.properties   (skipped if the file does not exist)
+//
+// Definitions that come later, whether included or not, override earlier ones.
+func NewPropertiesFileSource(path string, opts ...PropertiesOption) (*PropertiesSource, error) {
+	source := &PropertiesSource{
+		values: make(map[string]string),
+		files:  make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(source)
+	}
+
+	err := parsePropertiesFile(path, source, nil)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	values := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	return source, nil
+}
+
+func parsePropertiesFile(path string, source *PropertiesSource, includeStack []string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	for _, included := range includeStack {
+		if included == absPath {
+			return fmt.Errorf("include cycle detected: %s -> %s", strings.Join(includeStack, " -> "), absPath)
+		}
+	}
+	includeStack = append(includeStack, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if source.verifier != nil {
+		err := verifySignature(path, data, source.verifier)
+		if err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -29,26 +108,112 @@ func NewPropertiesFileSource(path string) (*PropertiesSource, error) {
 			continue
 		}
 
+		includePath, optional, isInclude := parseIncludeDirective(line)
+		if isInclude {
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(filepath.Dir(path), includePath)
+			}
+
+			if optional {
+				_, err := os.Stat(includePath)
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+			}
+
+			err := parsePropertiesFile(includePath, source, includeStack)
+			if err != nil {
+				return fmt.Errorf("failed to include %s from %s: %w", includePath, path, err)
+			}
+			continue
+		}
+
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			return nil, fmt.Errorf("malformed line: %s", line)
+			return fmt.Errorf("malformed line: %s", line)
 		}
 
 		key := strings.TrimSpace(parts[0])
 		value := strings.TrimSpace(parts[1])
 
-		values[key] = value
+		source.set(key, value, path)
 	}
 
 	err = scanner.Err()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse properties file %w", err)
+		return fmt.Errorf("failed to parse properties file %w", err)
 	}
 
-	return &PropertiesSource{values: values}, nil
+	return nil
+}
+
+// verifySignature checks data, read from path, against the signature stored
+// next to it
+func verifySignature(path string, data []byte, verifier Verifier) error {
+	signature, err := os.ReadFile(path + SignatureSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Not wrapped, a missing signature must not look like a missing file
+		return fmt.Errorf("%s is not signed, %s%s is missing", path, path, SignatureSuffix)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read signature of %s: %w", path, err)
+	}
+
+	err = verifier.Verify(SignedMessage(path, data), string(signature))
+	if err != nil {
+		return fmt.Errorf("signature verification failed for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// SignedMessage returns the message signed for the file at path holding data.
+// It includes the file's base name, so a signed app-dev.properties can't be
+// swapped in for app-prod.properties or an included file. An older signed
+// revision of the same file, or a file with the same name from another
+// directory, still verifies.
+func SignedMessage(path string, data []byte) []byte {
+	message := []byte(signatureContext + filepath.Base(path) + "\n")
+	return append(message, data...)
+}
+
+// parseIncludeDirective returns the path of an @include or @include? line
+func parseIncludeDirective(line string) (string, bool, bool) {
+	for _, directive := range []string{"@include?", "@include"} {
+		rest, found := strings.CutPrefix(line, directive)
+		if !found || rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+
+		return strings.TrimSpace(rest), directive == "@include?", true
+	}
+
+	return "", false, false
 }
 
 func (s *PropertiesSource) Get(key string) (string, bool) {
 	val, ok := s.values[key]
 	return val, ok
 }
+
+// Keys returns every key in the order it first appears in the file, included
+// files are expanded where the include directive is
+func (s *PropertiesSource) Keys() []string {
+	return append([]string{}, s.keys...)
+}
+
+// File returns the file that defines key, which is an included file when the
+// definition comes from one
+func (s *PropertiesSource) File(key string) (string, bool) {
+	file, ok := s.files[key]
+	return file, ok
+}
+
+func (s *PropertiesSource) set(key string, value string, file string) {
+	if _, exists := s.values[key]; !exists {
+		s.keys = append(s.keys, key)
+	}
+
+	s.values[key] = value
+	s.files[key] = file
+}
@@ -1,9 +1,14 @@
 package sources
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/Reinami/configprovider/pkg/cryptography"
 )
 
 func writeTmpProperties(t *testing.T, content string) string {
@@ -77,3 +82,197 @@ func TestNewPropertiesFileSource_NotFound(t *testing.T) {
 		t.Fatalf("expected file not found error, got none")
 	}
 }
+
+func writeTmpPropertiesFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+
+		err := os.MkdirAll(filepath.Dir(filePath), 0755)
+		if err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+
+		err = os.WriteFile(filePath, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write temp properties file %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestNewPropertiesFileSource_Include(t *testing.T) {
+	dir := writeTmpPropertiesFiles(t, map[string]string{
+		"service/app.properties": `
+HOST=overridden
+@include ../shared/common.properties
+@include? missing.properties
+PORT=9090
+`,
+		"shared/common.properties": `
+HOST=common.internal
+PORT=8080
+@include	nested.properties
+`,
+		"shared/nested.properties": `
+TIMEOUT=30
+`,
+	})
+
+	source, err := NewPropertiesFileSource(filepath.Join(dir, "service", "app.properties"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	tests := map[string]string{
+		"HOST":    "common.internal",
+		"PORT":    "9090",
+		"TIMEOUT": "30",
+	}
+
+	for key, expected := range tests {
+		got, ok := source.Get(key)
+		if !ok || got != expected {
+			t.Errorf("key %q: expected %q, got %q", key, expected, got)
+		}
+	}
+
+	files := map[string]string{
+		"HOST":    filepath.Join("shared", "common.properties"),
+		"PORT":    filepath.Join("service", "app.properties"),
+		"TIMEOUT": filepath.Join("shared", "nested.properties"),
+	}
+
+	for key, expected := range files {
+		got, ok := source.File(key)
+		if !ok || !strings.HasSuffix(got, expected) {
+			t.Errorf("key %q: expected to be defined in %q, got %q", key, expected, got)
+		}
+	}
+}
+
+func TestNewPropertiesFileSource_IncludeMissing(t *testing.T) {
+	dir := writeTmpPropertiesFiles(t, map[string]string{
+		"app.properties": "@include missing.properties\n",
+	})
+
+	_, err := NewPropertiesFileSource(filepath.Join(dir, "app.properties"))
+	if err == nil {
+		t.Fatalf("expected error for missing include, got none")
+	}
+}
+
+func TestNewPropertiesFileSource_IncludeCycle(t *testing.T) {
+	dir := writeTmpPropertiesFiles(t, map[string]string{
+		"a.properties": "@include b.properties\n",
+		"b.properties": "@include a.properties\n",
+	})
+
+	_, err := NewPropertiesFileSource(filepath.Join(dir, "a.properties"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle detected") {
+		t.Fatalf("expected include cycle error, got: %v", err)
+	}
+}
+
+func signTmpPropertiesFiles(t *testing.T, signer *cryptography.Ed25519Signer, paths ...string) {
+	t.Helper()
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+
+		err = os.WriteFile(path+SignatureSuffix, []byte(signer.Sign(SignedMessage(path, data))+"\n"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write signature of %s: %v", path, err)
+		}
+	}
+}
+
+func TestNewPropertiesFileSource_SignatureVerification(t *testing.T) {
+	publicKey, privateKey, err := cryptography.GenerateEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	signer, _ := cryptography.NewEd25519Signer(privateKey)
+	verifier, _ := cryptography.NewEd25519Verifier(publicKey)
+
+	dir := writeTmpPropertiesFiles(t, map[string]string{
+		"app.properties":    "FEATURE.BETA=false\n@include common.properties\n",
+		"common.properties": "URL=https://example.com\n",
+	})
+	appPath := filepath.Join(dir, "app.properties")
+	commonPath := filepath.Join(dir, "common.properties")
+
+	signTmpPropertiesFiles(t, signer, appPath, commonPath)
+
+	source, err := NewPropertiesFileSource(appPath, WithSignatureVerifier(verifier))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if url, _ := source.Get("URL"); url != "https://example.com" {
+		t.Errorf("expected included value, got %q", url)
+	}
+
+	err = os.WriteFile(commonPath, []byte("URL=https://attacker.example\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to tamper with file: %v", err)
+	}
+
+	_, err = NewPropertiesFileSource(appPath, WithSignatureVerifier(verifier))
+	if err == nil || !strings.Contains(err.Error(), "signature verification failed for "+commonPath) {
+		t.Errorf("expected signature error for the included file, got: %v", err)
+	}
+
+	err = os.Remove(appPath + SignatureSuffix)
+	if err != nil {
+		t.Fatalf("failed to remove signature: %v", err)
+	}
+
+	_, err = NewPropertiesFileSource(appPath, WithSignatureVerifier(verifier))
+	if err == nil || !strings.Contains(err.Error(), "is not signed") || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected unsigned file error, got: %v", err)
+	}
+}
+
+func TestNewPropertiesFileSource_SignatureBoundToFileName(t *testing.T) {
+	publicKey, privateKey, err := cryptography.GenerateEd25519KeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+
+	signer, _ := cryptography.NewEd25519Signer(privateKey)
+	verifier, _ := cryptography.NewEd25519Verifier(publicKey)
+
+	dir := writeTmpPropertiesFiles(t, map[string]string{
+		"app-dev.properties":  "DEBUG=true\n",
+		"app-prod.properties": "DEBUG=false\n",
+	})
+	devPath := filepath.Join(dir, "app-dev.properties")
+	prodPath := filepath.Join(dir, "app-prod.properties")
+
+	signTmpPropertiesFiles(t, signer, devPath, prodPath)
+
+	// Replace the prod file and its signature with the signed dev file
+	for _, suffix := range []string{"", SignatureSuffix} {
+		data, err := os.ReadFile(devPath + suffix)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", devPath+suffix, err)
+		}
+
+		err = os.WriteFile(prodPath+suffix, data, 0644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", prodPath+suffix, err)
+		}
+	}
+
+	_, err = NewPropertiesFileSource(prodPath, WithSignatureVerifier(verifier))
+	if err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+		t.Errorf("expected signature error for the swapped file, got: %v", err)
+	}
+}